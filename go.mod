@@ -3,7 +3,6 @@ module github.com/Hoyaspark/go-partitioning-batch
 go 1.19
 
 require (
-	github.com/fatih/structs v1.1.0
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.28.0
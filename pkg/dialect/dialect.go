@@ -0,0 +1,253 @@
+package dialect
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"regexp"
+	"strings"
+)
+
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+)
+
+var (
+	identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	mapDriverToDialect = map[string]Dialect{
+		"postgres":         Postgres,
+		"pgx":              Postgres,
+		"pq-timeouts":      Postgres,
+		"cloudsqlpostgres": Postgres,
+		"nrpostgres":       Postgres,
+		"cockroach":        Postgres,
+		"mysql":            MySQL,
+		"nrmysql":          MySQL,
+		"sqlite3":          SQLite,
+		"sqlite":           SQLite,
+		"nrsqlite3":        SQLite,
+	}
+)
+
+// FromDriverName returns Dialect of the database/sql driver (example. sqlx.DB.DriverName())
+func FromDriverName(driverName string) (Dialect, error) {
+	if d, ok := mapDriverToDialect[driverName]; ok {
+		return d, nil
+	}
+	return "", errors.Errorf("unsupported driver [%s]", driverName)
+}
+
+func (d Dialect) Validate() error {
+	switch d {
+	case Postgres, MySQL, SQLite:
+		return nil
+	}
+	return errors.Errorf("unsupported dialect [%s]", d)
+}
+
+// Quote returns the quoted identifier. "schema.table" is quoted for each part
+func (d Dialect) Quote(ident string) string {
+	q := `"`
+	if d == MySQL {
+		q = "`"
+	}
+
+	parts := strings.Split(ident, ".")
+	for i, p := range parts {
+		parts[i] = q + strings.ReplaceAll(p, q, q+q) + q
+	}
+	return strings.Join(parts, ".")
+}
+
+// Insert returns "INSERT INTO table (columns) VALUES (:columns)" that can be executed by sqlx.NamedExec
+func (d Dialect) Insert(table string, columns []string) (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+
+	if table == "" {
+		return "", errors.New("empty table")
+	}
+
+	if len(columns) == 0 {
+		return "", errors.New("empty columns")
+	}
+
+	quoted, err := d.quoteColumns(columns)
+	if err != nil {
+		return "", err
+	}
+
+	named := make([]string, 0, len(columns))
+	for _, c := range columns {
+		named = append(named, ":"+c)
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.Quote(table), strings.Join(quoted, ", "), strings.Join(named, ", ")), nil
+}
+
+// InsertIgnore returns Insert that ignores the rows conflicting with a unique key
+func (d Dialect) InsertIgnore(table string, columns []string) (string, error) {
+	q, err := d.Insert(table, columns)
+	if err != nil {
+		return "", err
+	}
+
+	switch d {
+	case MySQL:
+		return strings.Replace(q, "INSERT INTO", "INSERT IGNORE INTO", 1), nil
+	case SQLite:
+		return strings.Replace(q, "INSERT INTO", "INSERT OR IGNORE INTO", 1), nil
+	default:
+		return q + " ON CONFLICT DO NOTHING", nil
+	}
+}
+
+// Upsert returns Insert that updates the columns except keys of the rows conflicting with keys
+// keys must be the primary key or a unique key of the table
+func (d Dialect) Upsert(table string, columns, keys []string) (string, error) {
+	q, err := d.Insert(table, columns)
+	if err != nil {
+		return "", err
+	}
+
+	if len(keys) == 0 {
+		return "", errors.New("empty key columns")
+	}
+
+	quotedKeys, err := d.quoteColumns(keys)
+	if err != nil {
+		return "", err
+	}
+
+	isKey := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	var sets []string
+	for _, c := range columns {
+		if isKey[c] {
+			continue
+		}
+
+		if d == MySQL {
+			sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", d.Quote(c), d.Quote(c)))
+		} else {
+			sets = append(sets, fmt.Sprintf("%s = excluded.%s", d.Quote(c), d.Quote(c)))
+		}
+	}
+
+	if d == MySQL {
+		// every column is a key. the conflicting row is kept as it is
+		if len(sets) == 0 {
+			sets = append(sets, fmt.Sprintf("%s = %s", quotedKeys[0], quotedKeys[0]))
+		}
+		return q + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "), nil
+	}
+
+	if len(sets) == 0 {
+		return fmt.Sprintf("%s ON CONFLICT (%s) DO NOTHING", q, strings.Join(quotedKeys, ", ")), nil
+	}
+
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", q, strings.Join(quotedKeys, ", "), strings.Join(sets, ", ")), nil
+}
+
+// DeleteRange returns "DELETE FROM table WHERE column >= :from AND column <= :to" that deletes the rows of a range partition
+func (d Dialect) DeleteRange(table, column string) (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+
+	if table == "" {
+		return "", errors.New("empty table")
+	}
+
+	quoted, err := d.quoteColumns([]string{column})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("DELETE FROM %s WHERE %s >= :from AND %s <= :to", d.Quote(table), quoted[0], quoted[0]), nil
+}
+
+func (d Dialect) quoteColumns(columns []string) ([]string, error) {
+	quoted := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if !identPattern.MatchString(c) {
+			return nil, errors.Errorf("invalid column name [%s]", c)
+		}
+		quoted = append(quoted, d.Quote(c))
+	}
+	return quoted, nil
+}
+
+// HashBucket returns the predicate that is true for the rows whose hashed column is in the bucket :bucket of :buckets.
+// Every row including NULL lands in exactly one bucket. Postgres hashes by MD5, MySQL by CRC32 and SQLite,
+// which has no hash function, by a polynomial hash of the text of the key computed for every row
+func (d Dialect) HashBucket(column string) (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+
+	for _, c := range strings.Split(column, ".") {
+		if !identPattern.MatchString(c) {
+			return "", errors.Errorf("invalid column name [%s]", column)
+		}
+	}
+
+	c := d.Quote(column)
+
+	switch d {
+	case Postgres:
+		// the first 32 bits of MD5 as a non-negative BIGINT. HASHTEXT is internal and can change between the versions
+		// CAST instead of :: because sqlx reads :: as an escaped colon of the named query
+		return fmt.Sprintf("MOD(CAST(CAST('x' || LPAD(SUBSTR(MD5(COALESCE(CAST(%s AS TEXT), '')), 1, 8), 16, '0') AS BIT(64)) AS BIGINT), :buckets) = :bucket", c), nil
+	case MySQL:
+		return fmt.Sprintf("MOD(CRC32(COALESCE(%s, '')), :buckets) = :bucket", c), nil
+	case SQLite:
+		// h = (h * 31 + code point) mod 2^32 over the characters of the text of the key by a correlated recursive CTE
+		return fmt.Sprintf("(WITH RECURSIVE h(s, i, v) AS (SELECT CAST(COALESCE(%s, '') AS TEXT), 0, 0 "+
+			"UNION ALL SELECT s, i + 1, (v * 31 + UNICODE(SUBSTR(s, i + 1, 1))) %% 4294967296 FROM h WHERE i < LENGTH(s)) "+
+			"SELECT v FROM h WHERE i = LENGTH(s)) %% :buckets = :bucket", c), nil
+	}
+
+	return "", errors.Errorf("hash bucket is not supported by dialect [%s]", d)
+}
+
+// Quantiles returns the query of the min, max and count of each :tiles tile of the column that can be scanned into parallel.Quantile
+func (d Dialect) Quantiles(table, column string) (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+
+	if table == "" {
+		return "", errors.New("empty table")
+	}
+
+	if !identPattern.MatchString(column) {
+		return "", errors.Errorf("invalid column name [%s]", column)
+	}
+
+	c := d.Quote(column)
+
+	return fmt.Sprintf("SELECT MIN(%s) AS min, MAX(%s) AS max, COUNT(*) AS count FROM (SELECT %s, NTILE(:tiles) OVER (ORDER BY %s) AS tile FROM %s WHERE %s IS NOT NULL) t GROUP BY tile ORDER BY tile",
+		c, c, c, c, d.Quote(table), c), nil
+}
+
+// MaxPlaceholders returns the maximum number of bind variables in a statement
+func (d Dialect) MaxPlaceholders() int {
+	switch d {
+	case Postgres, MySQL:
+		return 65535
+	case SQLite:
+		// SQLITE_MAX_VARIABLE_NUMBER since 3.32.0
+		return 32766
+	}
+	return 999
+}
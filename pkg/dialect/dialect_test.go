@@ -0,0 +1,156 @@
+package dialect
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Insert(t *testing.T) {
+	t.Run("generate insert query for each dialect", func(t *testing.T) {
+		columns := []string{"id", "title"}
+
+		testData := []struct {
+			dialect Dialect
+			query   string
+		}{
+			{Postgres, `INSERT INTO "public"."faqs" ("id", "title") VALUES (:id, :title)`},
+			{MySQL, "INSERT INTO `public`.`faqs` (`id`, `title`) VALUES (:id, :title)"},
+			{SQLite, `INSERT INTO "public"."faqs" ("id", "title") VALUES (:id, :title)`},
+		}
+
+		for _, td := range testData {
+			q, err := td.dialect.Insert("public.faqs", columns)
+
+			assert.NoError(t, err)
+			assert.Equal(t, td.query, q)
+		}
+	})
+
+	t.Run("fail with bad settings", func(t *testing.T) {
+		_, err := Postgres.Insert("faqs", nil)
+		assert.Error(t, err)
+
+		_, err = Postgres.Insert("", []string{"id"})
+		assert.Error(t, err)
+
+		_, err = Postgres.Insert("faqs", []string{"id; DROP TABLE faqs"})
+		assert.Error(t, err)
+
+		_, err = Dialect("oracle").Insert("faqs", []string{"id"})
+		assert.Error(t, err)
+	})
+}
+
+func Test_HashBucket(t *testing.T) {
+	t.Run("generate hash bucket predicate for each dialect", func(t *testing.T) {
+		q, err := Postgres.HashBucket("faqs.uuid")
+		assert.NoError(t, err)
+		assert.Equal(t, `MOD(CAST(CAST('x' || LPAD(SUBSTR(MD5(COALESCE(CAST("faqs"."uuid" AS TEXT), '')), 1, 8), 16, '0') AS BIT(64)) AS BIGINT), :buckets) = :bucket`, q)
+
+		q, err = MySQL.HashBucket("uuid")
+		assert.NoError(t, err)
+		assert.Equal(t, "MOD(CRC32(COALESCE(`uuid`, '')), :buckets) = :bucket", q)
+
+		q, err = SQLite.HashBucket("id")
+		assert.NoError(t, err)
+		assert.Equal(t, `(WITH RECURSIVE h(s, i, v) AS (SELECT CAST(COALESCE("id", '') AS TEXT), 0, 0 `+
+			`UNION ALL SELECT s, i + 1, (v * 31 + UNICODE(SUBSTR(s, i + 1, 1))) % 4294967296 FROM h WHERE i < LENGTH(s)) `+
+			`SELECT v FROM h WHERE i = LENGTH(s)) % :buckets = :bucket`, q)
+	})
+
+	t.Run("keep the casts of the predicate in the named query", func(t *testing.T) {
+		for _, d := range []Dialect{Postgres, MySQL, SQLite} {
+			q, err := d.HashBucket("uuid")
+			assert.NoError(t, err)
+
+			bound, args, err := sqlx.Named("SELECT * FROM faqs WHERE "+q, map[string]any{"buckets": 4, "bucket": 1})
+			assert.NoError(t, err)
+			assert.NotContains(t, bound, ":")
+			assert.NotEmpty(t, args)
+		}
+	})
+
+	t.Run("fail with bad settings", func(t *testing.T) {
+		_, err := Dialect("oracle").HashBucket("uuid")
+		assert.Error(t, err)
+
+		_, err = Postgres.HashBucket("uuid) OR (1=1")
+		assert.Error(t, err)
+	})
+}
+
+func Test_Quantiles(t *testing.T) {
+	t.Run("generate ntile query", func(t *testing.T) {
+		q, err := Postgres.Quantiles("faqs", "id")
+
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT MIN("id") AS min, MAX("id") AS max, COUNT(*) AS count FROM (SELECT "id", NTILE(:tiles) OVER (ORDER BY "id") AS tile FROM "faqs" WHERE "id" IS NOT NULL) t GROUP BY tile ORDER BY tile`, q)
+	})
+
+	t.Run("fail with bad settings", func(t *testing.T) {
+		_, err := MySQL.Quantiles("", "id")
+		assert.Error(t, err)
+
+		_, err = MySQL.Quantiles("faqs", "id desc")
+		assert.Error(t, err)
+	})
+}
+
+func Test_IdempotentWrite(t *testing.T) {
+	t.Run("generate insert ignore and upsert for each dialect", func(t *testing.T) {
+		columns := []string{"id", "title"}
+
+		testData := []struct {
+			dialect Dialect
+			ignore  string
+			upsert  string
+		}{
+			{Postgres,
+				`INSERT INTO "faqs" ("id", "title") VALUES (:id, :title) ON CONFLICT DO NOTHING`,
+				`INSERT INTO "faqs" ("id", "title") VALUES (:id, :title) ON CONFLICT ("id") DO UPDATE SET "title" = excluded."title"`},
+			{MySQL,
+				"INSERT IGNORE INTO `faqs` (`id`, `title`) VALUES (:id, :title)",
+				"INSERT INTO `faqs` (`id`, `title`) VALUES (:id, :title) ON DUPLICATE KEY UPDATE `title` = VALUES(`title`)"},
+			{SQLite,
+				`INSERT OR IGNORE INTO "faqs" ("id", "title") VALUES (:id, :title)`,
+				`INSERT INTO "faqs" ("id", "title") VALUES (:id, :title) ON CONFLICT ("id") DO UPDATE SET "title" = excluded."title"`},
+		}
+
+		for _, td := range testData {
+			q, err := td.dialect.InsertIgnore("faqs", columns)
+			assert.NoError(t, err)
+			assert.Equal(t, td.ignore, q)
+
+			q, err = td.dialect.Upsert("faqs", columns, []string{"id"})
+			assert.NoError(t, err)
+			assert.Equal(t, td.upsert, q)
+		}
+	})
+
+	t.Run("keep the row when every column is a key", func(t *testing.T) {
+		q, err := Postgres.Upsert("faqs", []string{"id"}, []string{"id"})
+		assert.NoError(t, err)
+		assert.Equal(t, `INSERT INTO "faqs" ("id") VALUES (:id) ON CONFLICT ("id") DO NOTHING`, q)
+
+		q, err = MySQL.Upsert("faqs", []string{"id"}, []string{"id"})
+		assert.NoError(t, err)
+		assert.Equal(t, "INSERT INTO `faqs` (`id`) VALUES (:id) ON DUPLICATE KEY UPDATE `id` = `id`", q)
+	})
+
+	t.Run("generate delete of partition range", func(t *testing.T) {
+		q, err := SQLite.DeleteRange("faqs", "id")
+		assert.NoError(t, err)
+		assert.Equal(t, `DELETE FROM "faqs" WHERE "id" >= :from AND "id" <= :to`, q)
+
+		_, err = SQLite.DeleteRange("faqs", "id; --")
+		assert.Error(t, err)
+	})
+}
+
+func Test_MaxPlaceholders(t *testing.T) {
+	assert.Equal(t, 65535, Postgres.MaxPlaceholders())
+	assert.Equal(t, 65535, MySQL.MaxPlaceholders())
+	assert.Equal(t, 32766, SQLite.MaxPlaceholders())
+	assert.Equal(t, 999, Dialect("oracle").MaxPlaceholders())
+}
@@ -18,6 +18,7 @@ const (
 	KindFatal
 	KindConflict
 	KindTimeout
+	KindInvalidItem
 )
 
 var (
@@ -29,6 +30,7 @@ var (
 		KindFatal:               http.StatusInternalServerError,
 		KindConflict:            http.StatusConflict,
 		KindTimeout:             http.StatusRequestTimeout,
+		KindInvalidItem:         http.StatusUnprocessableEntity,
 	}
 )
 
@@ -106,6 +108,25 @@ func KindToHTTPStatus(kind Kind) int {
 	return mapKindTohttpStatus[KindUndefined]
 }
 
+// HTTPStatusToKind returns Kind of the failed response status. KindUndefined if the status is not mapped
+func HTTPStatusToKind(status int) Kind {
+	switch status {
+	case http.StatusBadRequest:
+		return KindBadRequest
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusConflict:
+		return KindConflict
+	case http.StatusRequestTimeout:
+		return KindTimeout
+	case http.StatusUnprocessableEntity:
+		return KindInvalidItem
+	case http.StatusInternalServerError:
+		return KindInternalServerError
+	}
+	return KindUndefined
+}
+
 func (e *Error) Error() string {
 	if e.Err == nil {
 		e.Err = errors.New("")
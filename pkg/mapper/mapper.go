@@ -0,0 +1,334 @@
+package mapper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"github.com/pkg/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tag is the struct tag of the column names. It is the tag sqlx reads with
+// `db:"name"` names the column, `db:"name,omitempty"` binds NULL for the zero value and `db:"-"` ignores the field
+// The untagged fields are named in lower case, and the fields of the untagged embedded structs are promoted
+const Tag = "db"
+
+// Field is a column mapped to a struct field
+type Field struct {
+	Name      string
+	Index     []int // see reflect.Value.FieldByIndex
+	OmitEmpty bool
+}
+
+// Fields is the columns of a struct type. It is built once per type and shared by the goroutines
+type Fields struct {
+	typ    reflect.Type
+	list   []Field
+	byName map[string]int
+}
+
+var cache sync.Map // reflect.Type -> *Fields
+
+// For returns Fields of T. T can be a pointer to a struct
+func For[T any]() *Fields {
+	return Of(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Of returns Fields of the struct type t or of the struct t points to
+func Of(t reflect.Type) *Fields {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if f, ok := cache.Load(t); ok {
+		return f.(*Fields)
+	}
+
+	f := &Fields{typ: t, byName: make(map[string]int)}
+	if t.Kind() == reflect.Struct {
+		f.collect(t, nil)
+	}
+
+	actual, _ := cache.LoadOrStore(t, f)
+	return actual.(*Fields)
+}
+
+// collect adds the fields of t. A shallower field hides the deeper one of the same name like the promoted fields of Go
+func (f *Fields) collect(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag, hasTag := sf.Tag.Lookup(Tag)
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		idx := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		embedded := sf.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if sf.Anonymous && (!hasTag || name == "") && embedded.Kind() == reflect.Struct {
+			f.collect(embedded, idx)
+			continue
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		field := Field{Name: name, Index: idx, OmitEmpty: hasOption(opts, "omitempty")}
+		if at, ok := f.byName[name]; ok {
+			if len(f.list[at].Index) > len(idx) {
+				f.list[at] = field
+			}
+			continue
+		}
+
+		f.byName[name] = len(f.list)
+		f.list = append(f.list, field)
+	}
+}
+
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// Type returns the struct type of the fields
+func (f *Fields) Type() reflect.Type {
+	return f.typ
+}
+
+// Names returns the column names in the order of the struct fields
+func (f *Fields) Names() []string {
+	names := make([]string, 0, len(f.list))
+	for _, field := range f.list {
+		names = append(names, field.Name)
+	}
+	return names
+}
+
+// Field returns the field of the column name
+func (f *Fields) Field(name string) (Field, bool) {
+	if at, ok := f.byName[name]; ok {
+		return f.list[at], true
+	}
+	return Field{}, false
+}
+
+// Select returns the fields of names. Resolve names once and pass the result to Values or Pointers for every row
+func (f *Fields) Select(names []string) ([]Field, error) {
+	fields := make([]Field, 0, len(names))
+	for _, name := range names {
+		field, ok := f.Field(name)
+		if !ok {
+			return nil, errors.Errorf("missing field of [%s] in %s", name, f.typ)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Values appends the values of fields of v to args. A field in a nil embedded pointer and an empty omitempty field are nil
+func (f *Fields) Values(v any, fields []Field, args []any) []any {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	for _, field := range fields {
+		fv, ok := fieldByIndex(rv, field.Index, false)
+		if !ok || (field.OmitEmpty && fv.IsZero()) {
+			args = append(args, nil)
+			continue
+		}
+		args = append(args, fv.Interface())
+	}
+
+	return args
+}
+
+// Map returns the values of every field of v by the column names
+func (f *Fields) Map(v any) map[string]any {
+	values := f.Values(v, f.list, make([]any, 0, len(f.list)))
+
+	m := make(map[string]any, len(f.list))
+	for i, field := range f.list {
+		m[field.Name] = values[i]
+	}
+	return m
+}
+
+// Pointers appends the addresses of fields of v to dst for database/sql Scan. v must be a pointer
+// The nil embedded pointers on the way are allocated
+func (f *Fields) Pointers(v any, fields []Field, dst []any) ([]any, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, errors.Errorf("cannot scan into non-pointer %T", v)
+	}
+	rv = rv.Elem()
+
+	for _, field := range fields {
+		fv, _ := fieldByIndex(rv, field.Index, true)
+		dst = append(dst, fv.Addr().Interface())
+	}
+
+	return dst, nil
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex that allocates the nil embedded pointers if alloc or reports false
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// Parse sets the value of text to the field ptr points to (example. a CSV cell into a pointer of Pointers)
+// The empty text is the zero value (NULL of sql.Scanner), and encoding.TextUnmarshaler (example. time.Time as RFC 3339) parses it by itself
+// The nullable types like sql.NullInt64 scan the text parsed into the type of their value, and the other sql.Scanner scan the text
+func Parse(ptr any, text string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = text
+		return nil
+	case sql.Scanner:
+		if text == "" {
+			return p.Scan(nil)
+		}
+		if value, ok := nullValue(p); ok {
+			if err := Parse(value.Interface(), text); err != nil {
+				return err
+			}
+			return p.Scan(value.Elem().Interface())
+		}
+		return p.Scan(text)
+	case encoding.TextUnmarshaler:
+		if text == "" {
+			return nil
+		}
+		return p.UnmarshalText([]byte(text))
+	}
+
+	v := reflect.ValueOf(ptr).Elem()
+	if text == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		return Parse(v.Interface(), text)
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return errors.Errorf("cannot parse text into %s", v.Type())
+	}
+
+	return nil
+}
+
+// nullValue returns the pointer to a new value of the type the nullable scanner holds (example. int64 of sql.NullInt64, T of sql.Null[T])
+func nullValue(scanner sql.Scanner) (reflect.Value, bool) {
+	v := reflect.ValueOf(scanner)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	t := v.Elem().Type()
+	if t.NumField() != 2 || t.Field(1).Name != "Valid" || t.Field(1).Type.Kind() != reflect.Bool {
+		return reflect.Value{}, false
+	}
+
+	return reflect.New(t.Field(0).Type), true
+}
+
+// Format returns the text of a value of Values (example. a CSV cell). nil is the empty text
+// driver.Valuer and encoding.TextMarshaler format it by themselves, and time.Time is RFC 3339 with nanoseconds
+func Format(v any) (string, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return "", nil
+	}
+
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	case driver.Valuer:
+		value, err := t.Value()
+		if err != nil {
+			return "", err
+		}
+		return Format(value)
+	case encoding.TextMarshaler:
+		text, err := t.MarshalText()
+		return string(text), err
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		return Format(rv.Elem().Interface())
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits()), nil
+	}
+
+	return "", errors.Errorf("cannot format %T as text", v)
+}
@@ -0,0 +1,136 @@
+package mapper
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type audit struct {
+	CreatedAt time.Time  `db:"created_at"`
+	DeletedAt *time.Time `db:"deleted_at,omitempty"`
+}
+
+type Author struct {
+	AuthorId int64 `db:"author_id"`
+	Title    string
+}
+
+type faq struct {
+	Id    int64  `db:"id"`
+	Title string `db:"title"`
+	Memo  string `db:"-"`
+	Score int64  `db:"score,omitempty"`
+	audit
+	*Author
+	secret string
+}
+
+func Test_Fields(t *testing.T) {
+	t.Run("map columns by db tags with embedded structs", func(t *testing.T) {
+		f := For[*faq]()
+
+		assert.Equal(t, []string{"id", "title", "score", "created_at", "deleted_at", "author_id"}, f.Names())
+		assert.Same(t, f, For[faq]())
+
+		title, ok := f.Field("title")
+		assert.True(t, ok)
+		assert.Equal(t, []int{1}, title.Index)
+
+		_, ok = f.Field("memo")
+		assert.False(t, ok)
+	})
+
+	t.Run("bind nil for omitempty and nil embedded pointers", func(t *testing.T) {
+		f := For[faq]()
+		now := time.Now()
+
+		m := f.Map(faq{Id: 1, Title: "a", audit: audit{CreatedAt: now}})
+		assert.Equal(t, map[string]any{
+			"id": int64(1), "title": "a", "score": nil, "created_at": now, "deleted_at": nil, "author_id": nil,
+		}, m)
+
+		fields, err := f.Select([]string{"author_id", "score"})
+		assert.NoError(t, err)
+		assert.Equal(t, []any{int64(7), int64(3)}, f.Values(&faq{Score: 3, Author: &Author{AuthorId: 7}}, fields, nil))
+
+		_, err = f.Select([]string{"body"})
+		assert.Error(t, err)
+	})
+
+	t.Run("scan into the fields allocating embedded pointers", func(t *testing.T) {
+		f := For[faq]()
+		fields, err := f.Select([]string{"id", "author_id"})
+		assert.NoError(t, err)
+
+		var item faq
+		ptrs, err := f.Pointers(&item, fields, nil)
+		assert.NoError(t, err)
+
+		*(ptrs[0].(*int64)) = 1
+		*(ptrs[1].(*int64)) = 2
+		assert.Equal(t, int64(1), item.Id)
+		assert.Equal(t, int64(2), item.AuthorId)
+
+		_, err = f.Pointers(item, fields, nil)
+		assert.Error(t, err)
+	})
+}
+
+func Test_ParseFormat(t *testing.T) {
+	t.Run("round trip the text of the fields", func(t *testing.T) {
+		type row struct {
+			Id      int64
+			Score   *float64
+			Ok      bool
+			At      time.Time
+			Skipped *time.Time
+		}
+
+		var r row
+		f := For[row]()
+		ptrs, err := f.Pointers(&r, f.list, nil)
+		assert.NoError(t, err)
+
+		texts := []string{"7", "0.25", "true", "2024-01-02T03:04:05.5Z", ""}
+		for i, text := range texts {
+			assert.NoError(t, Parse(ptrs[i], text))
+		}
+		assert.Equal(t, int64(7), r.Id)
+		assert.Equal(t, 0.25, *r.Score)
+		assert.Nil(t, r.Skipped)
+
+		for i, v := range f.Values(r, f.list, nil) {
+			text, err := Format(v)
+			assert.NoError(t, err)
+			assert.Equal(t, texts[i], text)
+		}
+
+		assert.Error(t, Parse(ptrs[0], "seven"))
+	})
+
+	t.Run("parse the nullable scanners", func(t *testing.T) {
+		var id sql.NullInt64
+		assert.NoError(t, Parse(&id, "7"))
+		assert.Equal(t, sql.NullInt64{Int64: 7, Valid: true}, id)
+		assert.NoError(t, Parse(&id, ""))
+		assert.False(t, id.Valid)
+		assert.Error(t, Parse(&id, "seven"))
+
+		var at sql.NullTime
+		assert.NoError(t, Parse(&at, "2024-01-02T03:04:05Z"))
+		assert.Equal(t, sql.NullTime{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}, at)
+
+		var score sql.NullFloat64
+		assert.NoError(t, Parse(&score, "0.5"))
+		assert.Equal(t, 0.5, score.Float64)
+
+		text, err := Format(at)
+		assert.NoError(t, err)
+		assert.Equal(t, "2024-01-02T03:04:05Z", text)
+
+		_, err = Format([]int{1})
+		assert.Error(t, err)
+	})
+}
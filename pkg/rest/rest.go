@@ -0,0 +1,247 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetryWait = time.Second
+	maxRetryWait     = time.Minute
+)
+
+// Policy is the rate limit and the retries of the requests of a reader or a writer
+type Policy struct {
+	RateLimit   float64       // requests per second to a host, shared by the policies of the same RateLimit in the process. not limited if 0
+	Concurrency int           // requests in flight to a host, shared by the policies of the same Concurrency in the process. not limited if 0
+	MaxRetries  int           // retries of the transient failures. see Transient
+	RetryWait   time.Duration // wait before the first retry doubled every retry up to 1m. 1s if 0. Retry-After of the response up to 1m has priority
+}
+
+// Response is the response read by Do
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// StatusError is the response that failed with the status not in 2xx
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	body := e.Body
+	if len(body) > 256 {
+		body = body[:256]
+	}
+	return "status " + strconv.Itoa(e.StatusCode) + ": " + string(body)
+}
+
+// Transient returns whether the request failed with the status can succeed when it is retried (408, 429 and 5xx)
+func Transient(statusCode int) bool {
+	return statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// Do sends the request built by newRequest until it succeeds with 2xx, fails with the status that is not Transient or runs out of retries
+// newRequest is called for every attempt, so the body is sent again
+func Do(ctx context.Context, client *http.Client, p Policy, newRequest func(context.Context) (*http.Request, error)) (*Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := limiterOf(req.URL.Host, p.RateLimit).wait(ctx); err != nil {
+			return nil, err
+		}
+
+		release, err := acquire(ctx, req.URL.Host, p.Concurrency)
+		if err != nil {
+			return nil, err
+		}
+		res, retryAfter, err := do(client, req)
+		release()
+		if err == nil {
+			return res, nil
+		}
+
+		// the connection errors are retried but not the canceled context
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		var statusErr *StatusError
+		if (errors.As(err, &statusErr) && !Transient(statusErr.StatusCode)) || attempt >= p.MaxRetries {
+			return nil, err
+		}
+
+		wait := backoff(p.RetryWait, attempt)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+
+		log.Warn().Err(err).Msgf("[%s %s] retry %d after %s", req.Method, req.URL.Redacted(), attempt+1, wait)
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do returns the response of 2xx or StatusError with Retry-After of the response
+func do(client *http.Client, req *http.Request) (*Response, time.Duration, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, retryAfter(res.Header.Get("Retry-After")), &StatusError{StatusCode: res.StatusCode, Body: body}
+	}
+
+	return &Response{StatusCode: res.StatusCode, Header: res.Header, Body: body}, 0, nil
+}
+
+// retryAfter parses Retry-After of seconds or http date. It is not longer than maxRetryWait
+func retryAfter(value string) time.Duration {
+	var wait time.Duration
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = time.Until(at)
+	}
+
+	if wait > maxRetryWait {
+		return maxRetryWait
+	}
+	return wait
+}
+
+func backoff(wait time.Duration, attempt int) time.Duration {
+	if wait <= 0 {
+		wait = defaultRetryWait
+	}
+	for i := 0; i < attempt && wait < maxRetryWait; i++ {
+		wait *= 2
+	}
+	if wait > maxRetryWait {
+		return maxRetryWait
+	}
+	return wait
+}
+
+// limiters are the limiters of the hosts and the rate shared by the readers and the writers
+var limiters sync.Map
+
+// limiter spaces the requests to a host by the interval of the rate
+type limiter struct {
+	interval time.Duration // 0 if not limited
+
+	mu   sync.Mutex
+	next time.Time // time the next request can be sent
+}
+
+// limiterOf returns the limiter of host and rate. The policies of another rate don't wait for each other
+func limiterOf(host string, rate float64) *limiter {
+	if rate <= 0 {
+		return &limiter{}
+	}
+
+	l, _ := limiters.LoadOrStore(host+"#"+strconv.FormatFloat(rate, 'g', -1, 64), &limiter{
+		interval: time.Duration(float64(time.Second) / rate),
+	})
+	return l.(*limiter)
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	at := time.Now()
+	if l.next.After(at) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// slots are the semaphores of the hosts and the concurrency shared by the readers and the writers
+var slots sync.Map
+
+// acquire waits for a slot of the requests in flight to host and returns the function releasing it
+func acquire(ctx context.Context, host string, concurrency int) (func(), error) {
+	if concurrency <= 0 {
+		return func() {}, nil
+	}
+
+	s, _ := slots.LoadOrStore(host+"#"+strconv.Itoa(concurrency), make(chan struct{}, concurrency))
+	sem := s.(chan struct{})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	}
+}
+
+// Field returns the json value of the dotted path (example. "data.items") in body. null if the path does not exist
+func Field(body []byte, path string) json.RawMessage {
+	value := json.RawMessage(body)
+	if path == "" {
+		return value
+	}
+
+	for _, name := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err != nil {
+			return json.RawMessage("null")
+		}
+		if value = object[name]; value == nil {
+			return json.RawMessage("null")
+		}
+	}
+
+	return value
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Do(t *testing.T) {
+	newRequest := func(url string) func(context.Context) (*http.Request, error) {
+		return func(ctx context.Context) (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		}
+	}
+
+	t.Run("retry the transient status until it succeeds", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				_, _ = w.Write([]byte("ok"))
+			}
+		}))
+		defer srv.Close()
+
+		res, err := Do(context.Background(), srv.Client(), Policy{MaxRetries: 2, RetryWait: time.Millisecond}, newRequest(srv.URL))
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", string(res.Body))
+		assert.Equal(t, int32(3), calls)
+	})
+
+	t.Run("fail without retries on the client error", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		_, err := Do(context.Background(), srv.Client(), Policy{MaxRetries: 3, RetryWait: time.Millisecond}, newRequest(srv.URL))
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(*StatusError).StatusCode)
+		assert.Equal(t, int32(1), calls)
+	})
+
+	t.Run("space the requests to a host by the rate limit", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := Do(context.Background(), srv.Client(), Policy{RateLimit: 50}, newRequest(srv.URL))
+			assert.NoError(t, err)
+		}
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	})
+
+	t.Run("not wait for the rate limit of another policy", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		assert.Same(t, limiterOf("example.com", 2), limiterOf("example.com", 2))
+		assert.NotSame(t, limiterOf("example.com", 2), limiterOf("example.com", 1000))
+
+		// the slow policy reserves the next request of its limiter 500ms later
+		for i := 0; i < 2; i++ {
+			_, err := Do(context.Background(), srv.Client(), Policy{RateLimit: 2}, newRequest(srv.URL))
+			assert.NoError(t, err)
+		}
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := Do(context.Background(), srv.Client(), Policy{RateLimit: 1000}, newRequest(srv.URL))
+			assert.NoError(t, err)
+		}
+		assert.Less(t, time.Since(start), 250*time.Millisecond)
+	})
+
+	t.Run("limit the requests in flight to a host", func(t *testing.T) {
+		var inFlight, peak int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for p := atomic.LoadInt32(&peak); n > p && !atomic.CompareAndSwapInt32(&peak, p, n); p = atomic.LoadInt32(&peak) {
+			}
+			time.Sleep(10 * time.Millisecond)
+		}))
+		defer srv.Close()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := Do(context.Background(), srv.Client(), Policy{Concurrency: 2}, newRequest(srv.URL))
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.LessOrEqual(t, peak, int32(2))
+	})
+
+	t.Run("double the wait of every retry", func(t *testing.T) {
+		assert.Equal(t, time.Second, backoff(0, 0))
+		assert.Equal(t, 400*time.Millisecond, backoff(100*time.Millisecond, 2))
+		assert.Equal(t, maxRetryWait, backoff(time.Second, 10))
+	})
+
+	t.Run("cap the wait of retry after", func(t *testing.T) {
+		assert.Equal(t, 2*time.Second, retryAfter("2"))
+		assert.Equal(t, maxRetryWait, retryAfter("86400"))
+		assert.Equal(t, maxRetryWait, retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)))
+		assert.Equal(t, time.Duration(0), retryAfter(""))
+	})
+
+	t.Run("stop the retries when the context is cancelled", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := Do(ctx, srv.Client(), Policy{MaxRetries: 3}, newRequest(srv.URL))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
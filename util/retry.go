@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -42,3 +43,38 @@ func RetryIfFailed(attempts int, dur time.Duration, f func() (interface{}, error
 	}
 	return value, nil
 }
+
+// RetryBackoff calls f up to attempts times and returns the last error as it is
+// The wait between the attempts starts from backoff and is doubled every time
+func RetryBackoff(attempts int, backoff time.Duration, f func() error) (err error) {
+	for i := 0; ; i++ {
+		err = f()
+		if err == nil || i >= (attempts-1) {
+			return
+		}
+
+		log.Println("retrying after error:", err)
+
+		<-time.After(backoff)
+		backoff *= 2
+	}
+}
+
+// RetryBackoffContext is RetryBackoff that stops retrying when ctx is cancelled
+func RetryBackoffContext(ctx context.Context, attempts int, backoff time.Duration, f func() error) (err error) {
+	for i := 0; ; i++ {
+		err = f()
+		if err == nil || i >= (attempts-1) || ctx.Err() != nil {
+			return
+		}
+
+		log.Println("retrying after error:", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,171 @@
+package monitoring
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type noopCollector struct{}
+
+func (nc noopCollector) PartitionStarted(string) {}
+
+func (nc noopCollector) PartitionFinished(string) {}
+
+func (nc noopCollector) ChunkWritten(string, ChunkStat) {}
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the chunk write latency histogram
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type partitionCounter struct {
+	read      int64
+	processed int64
+	written   int64
+	affected  int64
+	skipped   int64
+}
+
+// PrometheusExporter is Collector that exposes the metrics in the Prometheus text format
+// It can be served as http.Handler to be scraped while the job is running
+type PrometheusExporter struct {
+	mu            sync.Mutex
+	jobName       string
+	buckets       []float64
+	partitions    map[string]*partitionCounter
+	active        int64
+	latencyCounts []int64 // cumulative count of each bucket
+	latencySum    float64
+	latencyCount  int64
+}
+
+func NewPrometheusExporter(jobName string) *PrometheusExporter {
+	return &PrometheusExporter{
+		jobName:       jobName,
+		buckets:       DefaultLatencyBuckets,
+		partitions:    make(map[string]*partitionCounter),
+		latencyCounts: make([]int64, len(DefaultLatencyBuckets)),
+	}
+}
+
+func (pe *PrometheusExporter) PartitionStarted(partitionName string) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.counter(partitionName)
+	pe.active++
+}
+
+func (pe *PrometheusExporter) PartitionFinished(partitionName string) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.active--
+}
+
+func (pe *PrometheusExporter) ChunkWritten(partitionName string, stat ChunkStat) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pc := pe.counter(partitionName)
+	pc.read += stat.Read
+	pc.processed += stat.Processed
+	pc.written += stat.Written
+	pc.affected += stat.Affected
+	pc.skipped += stat.Skipped
+
+	if stat.Latency <= 0 {
+		return
+	}
+
+	sec := stat.Latency.Seconds()
+	for i, b := range pe.buckets {
+		if sec <= b {
+			pe.latencyCounts[i]++
+		}
+	}
+	pe.latencySum += sec
+	pe.latencyCount++
+}
+
+func (pe *PrometheusExporter) counter(partitionName string) *partitionCounter {
+	pc, ok := pe.partitions[partitionName]
+	if !ok {
+		pc = &partitionCounter{}
+		pe.partitions[partitionName] = pc
+	}
+	return pc
+}
+
+func (pe *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := pe.Write(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Write writes the metrics in the Prometheus text format
+func (pe *PrometheusExporter) Write(w io.Writer) error {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	var sb strings.Builder
+
+	names := make([]string, 0, len(pe.partitions))
+	for name := range pe.partitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	job := fmt.Sprintf(`job="%s"`, escapeLabel(pe.jobName))
+
+	metrics := []struct {
+		name  string
+		help  string
+		value func(*partitionCounter) int64
+	}{
+		{"rows_read", "Rows read", func(pc *partitionCounter) int64 { return pc.read }},
+		{"rows_processed", "Rows processed", func(pc *partitionCounter) int64 { return pc.processed }},
+		{"rows_written", "Rows written", func(pc *partitionCounter) int64 { return pc.written }},
+		{"rows_affected", "Rows affected by the writer", func(pc *partitionCounter) int64 { return pc.affected }},
+		{"rows_skipped", "Rows skipped by the fault tolerance", func(pc *partitionCounter) int64 { return pc.skipped }},
+	}
+
+	for _, m := range metrics {
+		var total int64
+
+		fmt.Fprintf(&sb, "# HELP batch_partition_%s_total %s by the partition\n", m.name, m.help)
+		fmt.Fprintf(&sb, "# TYPE batch_partition_%s_total counter\n", m.name)
+		for _, name := range names {
+			v := m.value(pe.partitions[name])
+			total += v
+			fmt.Fprintf(&sb, "batch_partition_%s_total{%s,partition=\"%s\"} %d\n", m.name, job, escapeLabel(name), v)
+		}
+
+		fmt.Fprintf(&sb, "# HELP batch_%s_total %s by the job\n", m.name, m.help)
+		fmt.Fprintf(&sb, "# TYPE batch_%s_total counter\n", m.name)
+		fmt.Fprintf(&sb, "batch_%s_total{%s} %d\n", m.name, job, total)
+	}
+
+	sb.WriteString("# HELP batch_active_partitions Partitions running now\n")
+	sb.WriteString("# TYPE batch_active_partitions gauge\n")
+	fmt.Fprintf(&sb, "batch_active_partitions{%s} %d\n", job, pe.active)
+
+	sb.WriteString("# HELP batch_chunk_write_duration_seconds Time taken to write a chunk\n")
+	sb.WriteString("# TYPE batch_chunk_write_duration_seconds histogram\n")
+	for i, b := range pe.buckets {
+		fmt.Fprintf(&sb, "batch_chunk_write_duration_seconds_bucket{%s,le=\"%g\"} %d\n", job, b, pe.latencyCounts[i])
+	}
+	fmt.Fprintf(&sb, "batch_chunk_write_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", job, pe.latencyCount)
+	fmt.Fprintf(&sb, "batch_chunk_write_duration_seconds_sum{%s} %g\n", job, pe.latencySum)
+	fmt.Fprintf(&sb, "batch_chunk_write_duration_seconds_count{%s} %d\n", job, pe.latencyCount)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
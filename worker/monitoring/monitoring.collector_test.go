@@ -0,0 +1,35 @@
+package monitoring
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_PrometheusExporter(t *testing.T) {
+	t.Run("expose partition and total metrics in text format", func(t *testing.T) {
+		pe := NewPrometheusExporter("faqs")
+
+		pe.PartitionStarted("pCtx0")
+		pe.PartitionStarted("pCtx1")
+		pe.ChunkWritten("pCtx0", ChunkStat{Read: 300, Processed: 299, Written: 299, Affected: 299, Skipped: 1, Latency: 20 * time.Millisecond})
+		pe.ChunkWritten("pCtx1", ChunkStat{Read: 100, Processed: 100, Written: 100, Affected: 90, Latency: 2 * time.Second})
+		pe.PartitionFinished("pCtx1")
+
+		rec := httptest.NewRecorder()
+		pe.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		body := rec.Body.String()
+
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, body, `batch_partition_rows_read_total{job="faqs",partition="pCtx0"} 300`)
+		assert.Contains(t, body, `batch_rows_read_total{job="faqs"} 400`)
+		assert.Contains(t, body, `batch_rows_affected_total{job="faqs"} 389`)
+		assert.Contains(t, body, `batch_rows_skipped_total{job="faqs"} 1`)
+		assert.Contains(t, body, `batch_active_partitions{job="faqs"} 1`)
+		assert.Contains(t, body, `batch_chunk_write_duration_seconds_bucket{job="faqs",le="0.025"} 1`)
+		assert.Contains(t, body, `batch_chunk_write_duration_seconds_bucket{job="faqs",le="2.5"} 2`)
+		assert.Contains(t, body, `batch_chunk_write_duration_seconds_count{job="faqs"} 2`)
+	})
+}
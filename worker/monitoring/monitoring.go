@@ -6,6 +6,7 @@ type rowCountLog struct {
 	contextName      string
 	rowAffectedCount int64
 	rowCount         int64
+	skipCount        int64
 }
 
 func (rcl *rowCountLog) ContextName() string {
@@ -20,6 +21,10 @@ func (rcl *rowCountLog) RowCount() int64 {
 	return rcl.rowCount
 }
 
+func (rcl *rowCountLog) SkipCount() int64 {
+	return rcl.skipCount
+}
+
 func NewRowCountLog(contextName string, rowAffectedCount, rowCount int64) RowCountLog {
 	return &rowCountLog{
 		contextName:      contextName,
@@ -28,21 +33,45 @@ func NewRowCountLog(contextName string, rowAffectedCount, rowCount int64) RowCou
 	}
 }
 
+// NewSkipCountLog is NewRowCountLog with the number of items skipped by step.FaultTolerance
+func NewSkipCountLog(contextName string, rowAffectedCount, rowCount, skipCount int64) RowCountLog {
+	return &rowCountLog{
+		contextName:      contextName,
+		rowAffectedCount: rowAffectedCount,
+		rowCount:         rowCount,
+		skipCount:        skipCount,
+	}
+}
+
 type monitoring struct {
 	sendingErrCh    chan error
 	rowCountMonitor chan RowCountLog
 	runningCount    int64
+	collector       Collector
 }
 
 func NewMonitoring(len int) WorkerMonitoring {
+	return NewMonitoringWithCollector(len, nil)
+}
+
+// NewMonitoringWithCollector returns WorkerMonitoring that passes the metrics of the partitions to collector
+func NewMonitoringWithCollector(len int, collector Collector) WorkerMonitoring {
+	if collector == nil {
+		collector = noopCollector{}
+	}
 
 	return &monitoring{
 		sendingErrCh:    make(chan error),
 		rowCountMonitor: make(chan RowCountLog, len),
 		runningCount:    int64(len),
+		collector:       collector,
 	}
 }
 
+func (m *monitoring) Collector() Collector {
+	return m.collector
+}
+
 func (m *monitoring) Send(cnt RowCountLog) {
 	m.rowCountMonitor <- cnt
 }
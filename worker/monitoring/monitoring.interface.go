@@ -1,5 +1,7 @@
 package monitoring
 
+import "time"
+
 type WorkerMonitoring interface {
 	Send(cnt RowCountLog)
 	SendErr(err error)
@@ -7,10 +9,29 @@ type WorkerMonitoring interface {
 	ReceiveErr() <-chan error
 	IsFinish() bool
 	Finish()
+	Collector() Collector
+}
+
+// Collector receives the metrics of the running partitions (example. PrometheusExporter)
+type Collector interface {
+	PartitionStarted(partitionName string)
+	PartitionFinished(partitionName string)
+	ChunkWritten(partitionName string, stat ChunkStat)
 }
 
 type RowCountLog interface {
 	ContextName() string
 	RowAffectedCount() int64
 	RowCount() int64
+	SkipCount() int64
+}
+
+// ChunkStat is the count of the items since the previous chunk and the time taken to write the chunk
+type ChunkStat struct {
+	Read      int64
+	Processed int64
+	Written   int64
+	Affected  int64
+	Skipped   int64
+	Latency   time.Duration // zero when no chunk was written
 }
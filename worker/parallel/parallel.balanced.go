@@ -0,0 +1,83 @@
+package parallel
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"strconv"
+)
+
+// Quantile is a range of the key holding about 1/N of the rows (example. a tile of NTILE(N) OVER (ORDER BY id))
+type Quantile struct {
+	Min   int64 `db:"min"`
+	Max   int64 `db:"max"`
+	Count int64 `db:"count"` // estimated number of rows in [Min, Max]
+}
+
+// QuantileDB is ParallelDB that returns the approximate quantiles of the key by NTILE or sampling. see dialect.Dialect.Quantiles
+type QuantileDB interface {
+	ParallelDB
+	GetQuantiles(sourceName string, parallelSize int64) ([]Quantile, error)
+}
+
+var Balanced = ParallelTypeFunc{name: "Balanced", partition: partitionBalanced}
+
+// partitionBalanced divides [min, max] of the key into AutoIncrementIdType ranges holding roughly equal row counts
+func partitionBalanced(p *parallel) ([]Partition, error) {
+	op := er.GetOperator()
+
+	db, ok := p.db.(QuantileDB)
+	if !ok {
+		return nil, er.WrapOp(errors.New("ParallelDB must implement QuantileDB"), op)
+	}
+
+	quantiles, err := db.GetQuantiles(p.sourceName, p.parallelSize)
+	if err != nil {
+		log.Err(err).Msg("failed to get quantiles")
+		return nil, er.WrapOp(err, op)
+	}
+
+	if len(quantiles) == 0 {
+		return nil, er.WrapOp(errors.New("empty quantiles"), op)
+	}
+
+	for _, q := range quantiles {
+		if q.Min > q.Max {
+			return nil, er.WrapOp(errors.Errorf("invalid quantile [min:%d] [max:%d]", q.Min, q.Max), op)
+		}
+	}
+
+	// sort a copy not to reorder the quantiles of QuantileDB
+	quantiles = append([]Quantile(nil), quantiles...)
+	sort.Slice(quantiles, func(i, j int) bool { return quantiles[i].Max < quantiles[j].Max })
+
+	var pcs []*partition
+
+	start := quantiles[0].Min
+	for _, q := range quantiles {
+		// the tiles sharing a duplicated key are merged into the previous range
+		if q.Max < start {
+			pcs[len(pcs)-1].count += q.Count
+			continue
+		}
+
+		pcs = append(pcs, &partition{
+			parallelId:    "pCtx" + strconv.Itoa(len(pcs)),
+			min:           start,
+			max:           q.Max,
+			count:         q.Count,
+			partitionType: AutoIncrementIdType,
+		})
+
+		start = q.Max + 1
+	}
+
+	result := make([]Partition, 0, len(pcs))
+	for _, pc := range pcs {
+		log.Info().Msgf("[%s] divide into [min:%d] [max:%d] [estimated rows:%d]", pc.parallelId, pc.min, pc.max, pc.count)
+		result = append(result, pc)
+	}
+
+	return result, nil
+}
@@ -3,8 +3,34 @@ package parallel
 const (
 	AutoIncrementIdType int64 = iota
 	SortableIdType
+	KeysetType    // min and max are the sort keys of the first and the last item
+	TimeRangeType // min and max are [from, to) in unix nanoseconds. see TimeBounds
+	HashType      // min is the bucket and max is the number of buckets
+	ByteRangeType // min and max are [from, to) byte offsets of the file
+	FileType      // min is the index of the file in MatchFiles and max is the number of files. the name has the file name
 )
 
+// TypeName returns the name of the partition type (example. "AutoIncrementId")
+func TypeName(partitionType int64) string {
+	switch partitionType {
+	case AutoIncrementIdType:
+		return "AutoIncrementId"
+	case SortableIdType:
+		return "SortableId"
+	case KeysetType:
+		return "Keyset"
+	case TimeRangeType:
+		return "TimeRange"
+	case HashType:
+		return "Hash"
+	case ByteRangeType:
+		return "ByteRange"
+	case FileType:
+		return "File"
+	}
+	return "Unknown"
+}
+
 var EmptyPartition = NewPartition(0, 0, 0)
 
 type partition struct {
@@ -23,6 +49,17 @@ func NewPartition(min, max, count int64) Partition {
 	}
 }
 
+// RestorePartition returns Partition created by ParallelTypeFunc from the saved values (example. repository.JobRepository)
+func RestorePartition(parallelId string, min, max, count, partitionType int64) Partition {
+	return &partition{
+		parallelId:    parallelId,
+		min:           min,
+		max:           max,
+		count:         count,
+		partitionType: partitionType,
+	}
+}
+
 func (p *partition) PartitionName() string {
 	return p.parallelId
 }
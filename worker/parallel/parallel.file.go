@@ -0,0 +1,155 @@
+package parallel
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+var (
+	FileRanges = ParallelTypeFunc{name: "FileRanges", partition: partitionFileRanges}
+	Files      = ParallelTypeFunc{name: "Files", partition: partitionFiles}
+)
+
+// partitionFileRanges divides the file of sourceName into parallelSize byte ranges. Every range starts right after a line break
+// The records must not contain line breaks (example. NDJSON, CSV without quoted line breaks)
+func partitionFileRanges(p *parallel) ([]Partition, error) {
+	if p.parallelSize < 1 {
+		return nil, errors.Errorf("invalid parallel size [%d]", p.parallelSize)
+	}
+
+	f, err := os.Open(p.sourceName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	var pcs []Partition
+
+	start := int64(0)
+	for i := int64(1); i <= p.parallelSize && start < size; i++ {
+		end := size
+		if i < p.parallelSize {
+			if end, err = nextRecord(f, size*i/p.parallelSize); err != nil {
+				return nil, err
+			}
+		}
+
+		// the range is in a long record of the previous range
+		if end <= start {
+			continue
+		}
+
+		parallelId := "pCtx" + strconv.Itoa(len(pcs))
+		pcs = append(pcs, &partition{
+			parallelId:    parallelId,
+			min:           start,
+			max:           end,
+			partitionType: ByteRangeType,
+		})
+
+		log.Info().Msgf("[%s] divide into [from byte:%d] [to byte:%d]", parallelId, start, end)
+		start = end
+	}
+
+	return pcs, nil
+}
+
+// nextRecord returns the offset of the first record starting at or after offset
+func nextRecord(f *os.File, offset int64) (int64, error) {
+	if offset <= 0 {
+		return 0, nil
+	}
+
+	// the byte before offset tells whether a record starts at offset
+	r := bufio.NewReader(io.NewSectionReader(f, offset-1, math.MaxInt64-offset))
+	for pos := offset - 1; ; pos++ {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return pos, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b == '\n' {
+			return pos + 1, nil
+		}
+	}
+}
+
+// partitionFiles makes a partition of every file matched by the glob pattern of sourceName
+func partitionFiles(p *parallel) ([]Partition, error) {
+	files, err := MatchFiles(p.sourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var pcs []Partition
+
+	for i, file := range files {
+		parallelId := filePartitionName(i, file)
+
+		pcs = append(pcs, &partition{
+			parallelId:    parallelId,
+			min:           int64(i),
+			max:           int64(len(files)),
+			partitionType: FileType,
+		})
+
+		log.Info().Msgf("[%s] divide into [file:%s]", parallelId, file)
+	}
+
+	return pcs, nil
+}
+
+// filePartitionName returns the name of FileType partition having the name of its file (example. "pCtx0-faqs-1.csv")
+func filePartitionName(index int, file string) string {
+	return "pCtx" + strconv.Itoa(index) + "-" + filepath.Base(file)
+}
+
+// FileOf returns the file of FileType partition in the files of the glob pattern
+// It fails if the files are changed since partitioning, so a restarted partition doesn't read the file of another one
+func FileOf(pattern string, p Partition) (string, error) {
+	files, err := MatchFiles(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	if int64(len(files)) != p.Max() || p.Min() < 0 || p.Min() >= p.Max() {
+		return "", errors.Errorf("%d files match [%s] but partitioned into %d", len(files), pattern, p.Max())
+	}
+
+	file := files[p.Min()]
+	if filePartitionName(int(p.Min()), file) != p.PartitionName() {
+		return "", errors.Errorf("file [%s] is not the file of partition [%s]", file, p.PartitionName())
+	}
+
+	return file, nil
+}
+
+// MatchFiles returns the sorted files of the glob pattern. FileType partition is the index of its file. see FileOf
+func MatchFiles(pattern string) ([]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, errors.Errorf("no file matches [%s]", pattern)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
@@ -2,7 +2,9 @@ package parallel
 
 import (
 	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"sort"
 	"strconv"
 )
 
@@ -20,12 +22,22 @@ func NewParallel(sourceName string, db ParallelDB, parallelSize int64) Parallel
 	}
 }
 
-type ParallelTypeFunc func(*parallel) ([]Partition, error)
+// ParallelTypeFunc is the partitioning strategy of Parallel.Partition
+type ParallelTypeFunc struct {
+	name      string
+	partition func(*parallel) ([]Partition, error)
+}
+
+// Name returns the name of the partitioning strategy (example. "AutoIncrementId" for AutoIncrementId)
+func (ptf ParallelTypeFunc) Name() string {
+	return ptf.name
+}
 
 var (
-	AutoIncrementId ParallelTypeFunc = partitionAutoIncrementId
-	SortableId      ParallelTypeFunc = partitionSortableId
-	None            ParallelTypeFunc = partitionNone
+	AutoIncrementId = ParallelTypeFunc{name: "AutoIncrementId", partition: partitionAutoIncrementId}
+	SortableId      = ParallelTypeFunc{name: "SortableId", partition: partitionSortableId}
+	None            = ParallelTypeFunc{name: "None", partition: partitionNone}
+	SampledKey      = ParallelTypeFunc{name: "SampledKey", partition: partitionSampledKey}
 )
 
 func partitionNone(p *parallel) ([]Partition, error) {
@@ -124,6 +136,67 @@ func partitionSortableId(p *parallel) ([]Partition, error) {
 
 }
 
+func partitionSampledKey(p *parallel) ([]Partition, error) {
+	op := er.GetOperator()
+
+	db, ok := p.db.(KeyBoundaryDB)
+	if !ok {
+		return nil, er.WrapOp(errors.New("ParallelDB must implement KeyBoundaryDB"), op)
+	}
+
+	pCtx, err := p.db.GetSortBy(p.sourceName)
+	if err != nil {
+		log.Err(err).Msg("failed to get sort by")
+		return nil, er.WrapOp(err, op)
+	}
+
+	boundaries, err := db.GetBoundaryKeys(p.sourceName, p.parallelSize)
+	if err != nil {
+		log.Err(err).Msg("failed to get boundary keys")
+		return nil, er.WrapOp(err, op)
+	}
+
+	// sort and append to a copy not to change the slice of KeyBoundaryDB
+	keys := make([]int64, len(boundaries), len(boundaries)+1)
+	copy(keys, boundaries)
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	var pcs []Partition
+
+	minKey := pCtx.Min()
+	maxKey := pCtx.Max()
+
+	number := 0
+	start := minKey
+
+	for _, end := range append(keys, maxKey) {
+		// skip the boundaries duplicated or out of [min, max]
+		if end < start || end > maxKey {
+			continue
+		}
+
+		pcs = append(pcs, &partition{
+			parallelId:    "pCtx" + strconv.Itoa(number),
+			min:           start,
+			max:           end,
+			partitionType: KeysetType,
+		})
+
+		log.Info().Msgf("[%s] divide into [min key:%d] [max key:%d]", "pCtx"+strconv.Itoa(number), start, end)
+		start = end + 1
+		number++
+
+		if end == maxKey {
+			break
+		}
+	}
+
+	return pcs, nil
+}
+
 func (p *parallel) Partition(ptf ParallelTypeFunc) ([]Partition, error) {
-	return ptf(p)
+	if ptf.partition == nil {
+		return nil, errors.New("empty ParallelTypeFunc")
+	}
+	return ptf.partition(p)
 }
@@ -0,0 +1,35 @@
+package parallel
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"strconv"
+)
+
+// HashBucket divides the unordered keys (example. UUID) into parallelSize buckets. The query filters a bucket by dialect.Dialect.HashBucket
+// SQLite has no hash function, so its predicate hashes the text of the key row by row and is slower than Postgres and MySQL
+var HashBucket = ParallelTypeFunc{name: "HashBucket", partition: partitionHashBucket}
+
+// partitionHashBucket divides the source into parallelSize buckets of the hashed key. see dialect.Dialect.HashBucket
+func partitionHashBucket(p *parallel) ([]Partition, error) {
+	if p.parallelSize < 1 {
+		return nil, errors.Errorf("invalid parallel size [%d]", p.parallelSize)
+	}
+
+	var pcs []Partition
+
+	for i := int64(0); i < p.parallelSize; i++ {
+		parallelId := "pCtx" + strconv.FormatInt(i, 10)
+
+		pcs = append(pcs, &partition{
+			parallelId:    parallelId,
+			min:           i,
+			max:           p.parallelSize,
+			partitionType: HashType,
+		})
+
+		log.Info().Msgf("[%s] divide into [bucket:%d] of [%d]", parallelId, i, p.parallelSize)
+	}
+
+	return pcs, nil
+}
@@ -15,3 +15,10 @@ type Partition interface {
 type ParallelDB interface {
 	GetSortBy(string) (Partition, error)
 }
+
+// KeyBoundaryDB is ParallelDB that can sample the sort keys dividing the source into parallelSize ranges of similar row count
+// (example. SELECT id FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY id) rn FROM faqs) t WHERE rn % (cnt / parallelSize) = 0)
+type KeyBoundaryDB interface {
+	ParallelDB
+	GetBoundaryKeys(sourceName string, parallelSize int64) ([]int64, error)
+}
@@ -0,0 +1,125 @@
+package parallel
+
+import (
+	"strconv"
+	"sync"
+)
+
+// SplitPartition is AutoIncrementIdType partition whose unread remainder can be split while it is read
+// The reader claims [Min, to] before reading it, and Split only gives away the range above the claimed one
+type SplitPartition interface {
+	Partition
+	// Claim claims the range up to `to` and returns `to` limited to Max()
+	Claim(to int64) int64
+	// Remaining returns the size of the range not claimed yet. It is 0 until the first Claim
+	Remaining() int64
+	// Split shrinks the partition to the lower half of the remainder and returns the upper half as a new partition
+	// It does nothing if the remainder is smaller than twice minSize or commit fails. commit is called before the split is applied
+	Split(minSize int64, commit func(max int64, sub Partition) error) (Partition, bool, error)
+}
+
+type splitPartition struct {
+	mu      sync.Mutex
+	p       partition
+	claimed int64
+	started bool
+}
+
+// NewSplitPartition returns SplitPartition of p. p must be AutoIncrementIdType
+func NewSplitPartition(p Partition) SplitPartition {
+	return &splitPartition{
+		p: partition{
+			parallelId:    p.PartitionName(),
+			min:           p.Min(),
+			max:           p.Max(),
+			count:         p.Count(),
+			partitionType: p.Type(),
+		},
+	}
+}
+
+func (sp *splitPartition) PartitionName() string {
+	return sp.p.parallelId
+}
+
+func (sp *splitPartition) Min() int64 {
+	return sp.p.min
+}
+
+func (sp *splitPartition) Max() int64 {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	return sp.p.max
+}
+
+func (sp *splitPartition) Count() int64 {
+	return sp.p.count
+}
+
+func (sp *splitPartition) Type() int64 {
+	return sp.p.partitionType
+}
+
+func (sp *splitPartition) Claim(to int64) int64 {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if to > sp.p.max {
+		to = sp.p.max
+	}
+	if !sp.started || to > sp.claimed {
+		sp.claimed = to
+	}
+	sp.started = true
+
+	return to
+}
+
+func (sp *splitPartition) Remaining() int64 {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	return sp.remaining()
+}
+
+func (sp *splitPartition) remaining() int64 {
+	// the range before a restored checkpoint may be read already
+	if !sp.started || sp.p.partitionType != AutoIncrementIdType {
+		return 0
+	}
+	return sp.p.max - sp.claimed
+}
+
+func (sp *splitPartition) Split(minSize int64, commit func(max int64, sub Partition) error) (Partition, bool, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if minSize < 1 {
+		minSize = 1
+	}
+
+	remaining := sp.remaining()
+	if remaining < 2*minSize {
+		return nil, false, nil
+	}
+
+	mid := sp.claimed + remaining/2
+
+	sub := &partition{
+		parallelId:    sp.p.parallelId + "-" + strconv.FormatInt(mid+1, 10),
+		min:           mid + 1,
+		max:           sp.p.max,
+		partitionType: AutoIncrementIdType,
+	}
+
+	if commit != nil {
+		if err := commit(mid, sub); err != nil {
+			return nil, false, err
+		}
+	}
+
+	sp.p.max = mid
+
+	return sub, true, nil
+}
@@ -0,0 +1,149 @@
+package parallel
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"time"
+)
+
+type Interval int64
+
+const (
+	Hour Interval = iota
+	Day
+	Month
+)
+
+// TimeRangeDB is ParallelDB that returns the min and max time of the source (example. SELECT MIN(created_at), MAX(created_at) FROM faqs)
+type TimeRangeDB interface {
+	ParallelDB
+	GetTimeRange(sourceName string) (time.Time, time.Time, error)
+}
+
+var TimeSlices = ParallelTypeFunc{name: "TimeSlices", partition: partitionTimeSlices}
+
+// TimeInterval returns ParallelTypeFunc that divides the time range into the calendar intervals in the location of min time
+func TimeInterval(interval Interval) ParallelTypeFunc {
+	return ParallelTypeFunc{name: "TimeInterval", partition: func(p *parallel) ([]Partition, error) {
+		op := er.GetOperator()
+
+		minTime, maxTime, err := getTimeRange(p)
+		if err != nil {
+			return nil, er.WrapOp(err, op)
+		}
+
+		var bounds []time.Time
+		for start := truncate(minTime, interval); !start.After(maxTime); start = next(start, interval) {
+			bounds = append(bounds, start)
+		}
+		bounds = append(bounds, next(bounds[len(bounds)-1], interval))
+
+		// the first partition starts from min time
+		bounds[0] = minTime
+
+		return timePartitions(bounds), nil
+	}}
+}
+
+// partitionTimeSlices divides the time range into parallelSize slices of the same duration
+func partitionTimeSlices(p *parallel) ([]Partition, error) {
+	op := er.GetOperator()
+
+	minTime, maxTime, err := getTimeRange(p)
+	if err != nil {
+		return nil, er.WrapOp(err, op)
+	}
+
+	// max time is included in the last slice
+	end := maxTime.Add(time.Nanosecond)
+	slices := p.parallelSize
+	if slices < 1 {
+		slices = 1
+	}
+
+	size := end.Sub(minTime) / time.Duration(slices)
+	if size <= 0 {
+		size = end.Sub(minTime)
+	}
+
+	var bounds []time.Time
+	for start := minTime; start.Before(end); start = start.Add(size) {
+		bounds = append(bounds, start)
+	}
+	bounds = append(bounds, end)
+
+	// merge the remainder shorter than size into the last slice
+	if len(bounds) > 2 && int64(len(bounds)-1) > slices {
+		bounds = append(bounds[:len(bounds)-2], end)
+	}
+
+	return timePartitions(bounds), nil
+}
+
+func getTimeRange(p *parallel) (time.Time, time.Time, error) {
+	db, ok := p.db.(TimeRangeDB)
+	if !ok {
+		return time.Time{}, time.Time{}, errors.New("ParallelDB must implement TimeRangeDB")
+	}
+
+	minTime, maxTime, err := db.GetTimeRange(p.sourceName)
+	if err != nil {
+		log.Err(err).Msg("failed to get time range")
+		return time.Time{}, time.Time{}, err
+	}
+
+	if maxTime.Before(minTime) {
+		return time.Time{}, time.Time{}, errors.Errorf("invalid time range [min:%s] [max:%s]", minTime, maxTime)
+	}
+
+	return minTime, maxTime, nil
+}
+
+// timePartitions returns the partitions of [bounds[i], bounds[i+1])
+func timePartitions(bounds []time.Time) []Partition {
+	var pcs []Partition
+
+	for i := 0; i+1 < len(bounds); i++ {
+		parallelId := "pCtx" + strconv.Itoa(i)
+
+		pcs = append(pcs, &partition{
+			parallelId:    parallelId,
+			min:           bounds[i].UnixNano(),
+			max:           bounds[i+1].UnixNano(),
+			partitionType: TimeRangeType,
+		})
+
+		log.Info().Msgf("[%s] divide into [from:%s] [to:%s]", parallelId, bounds[i].Format(time.RFC3339Nano), bounds[i+1].Format(time.RFC3339Nano))
+	}
+
+	return pcs
+}
+
+// TimeBounds returns [from, to) of TimeRangeType partition in UTC
+func TimeBounds(p Partition) (time.Time, time.Time) {
+	return time.Unix(0, p.Min()).UTC(), time.Unix(0, p.Max()).UTC()
+}
+
+func truncate(t time.Time, interval Interval) time.Time {
+	switch interval {
+	case Hour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case Day:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func next(t time.Time, interval Interval) time.Time {
+	switch interval {
+	case Hour:
+		return t.Add(time.Hour)
+	case Day:
+		return t.AddDate(0, 0, 1)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
@@ -3,7 +3,11 @@ package parallel
 import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 func Test_SortableID(t *testing.T) {
@@ -67,17 +71,356 @@ func Test_AutoIncrementID(t *testing.T) {
 	})
 }
 
+func Test_SampledKey(t *testing.T) {
+	t.Run("divide with sampled boundary keys", func(t *testing.T) {
+		boundaries := make([]int64, 5, 10)
+		copy(boundaries, []int64{70000, 10, 500, 500, 95000})
+
+		pm := NewParallel("", &parallelDBMock{
+			min:        3,
+			max:        90000,
+			boundaries: boundaries,
+		}, 4)
+
+		result, err := pm.Partition(SampledKey)
+
+		assert.NoError(t, err)
+
+		mockData := []mockAutoIncrementIDData{
+			{3, 10},
+			{11, 500},
+			{501, 70000},
+			{70001, 90000},
+		}
+
+		assert.Len(t, result, len(mockData))
+		for i, r := range result {
+			assert.Equal(t, KeysetType, r.Type())
+			assert.Equal(t, mockData[i].min, r.Min())
+			assert.Equal(t, mockData[i].max, r.Max())
+		}
+
+		// the slice of KeyBoundaryDB is not sorted or appended in place
+		assert.Equal(t, []int64{70000, 10, 500, 500, 95000}, boundaries)
+		assert.Equal(t, []int64{70000, 10, 500, 500, 95000, 0}, boundaries[:6])
+	})
+}
+
+func Test_TimeRange(t *testing.T) {
+	minTime := time.Date(2024, 1, 30, 13, 20, 0, 0, time.UTC)
+	maxTime := time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+
+	t.Run("divide into calendar months", func(t *testing.T) {
+		pm := NewParallel("", &parallelDBMock{minTime: minTime, maxTime: maxTime}, 0)
+
+		result, err := pm.Partition(TimeInterval(Month))
+
+		assert.NoError(t, err)
+
+		mockData := [][2]time.Time{
+			{minTime, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+			{time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+			{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		}
+
+		assert.Len(t, result, len(mockData))
+		for i, r := range result {
+			from, to := TimeBounds(r)
+			assert.Equal(t, TimeRangeType, r.Type())
+			assert.Equal(t, mockData[i][0], from)
+			assert.Equal(t, mockData[i][1], to)
+		}
+	})
+
+	t.Run("divide into equal time slices covering max time", func(t *testing.T) {
+		pm := NewParallel("", &parallelDBMock{minTime: minTime, maxTime: maxTime}, 4)
+
+		result, err := pm.Partition(TimeSlices)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 4)
+
+		from, _ := TimeBounds(result[0])
+		_, to := TimeBounds(result[3])
+		assert.Equal(t, minTime, from)
+		assert.True(t, to.After(maxTime))
+
+		for i := 1; i < len(result); i++ {
+			assert.Equal(t, result[i-1].Max(), result[i].Min())
+		}
+	})
+
+	t.Run("single instant makes a partition", func(t *testing.T) {
+		pm := NewParallel("", &parallelDBMock{minTime: minTime, maxTime: minTime}, 4)
+
+		result, err := pm.Partition(TimeSlices)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+	})
+
+	t.Run("fail without TimeRangeDB", func(t *testing.T) {
+		pm := NewParallel("", &sortByDBMock{}, 4)
+
+		_, err := pm.Partition(TimeInterval(Day))
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_Balanced(t *testing.T) {
+	t.Run("divide skewed ids by quantiles", func(t *testing.T) {
+		quantiles := []Quantile{
+			{Min: 1001, Max: 1500, Count: 250},
+			{Min: 1, Max: 1000, Count: 250},
+			{Min: 1501, Max: 900000, Count: 250},
+			{Min: 1500, Max: 1500, Count: 250},
+		}
+		pm := NewParallel("", &parallelDBMock{quantiles: quantiles}, 4)
+
+		result, err := pm.Partition(Balanced)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1001), quantiles[0].Min, "the quantiles of QuantileDB must not be reordered")
+
+		mockData := []struct {
+			min, max, count int64
+		}{
+			{1, 1000, 250},
+			{1001, 1500, 500},
+			{1501, 900000, 250},
+		}
+
+		assert.Len(t, result, len(mockData))
+		for i, r := range result {
+			assert.Equal(t, AutoIncrementIdType, r.Type())
+			assert.Equal(t, mockData[i].min, r.Min())
+			assert.Equal(t, mockData[i].max, r.Max())
+			assert.Equal(t, mockData[i].count, r.Count())
+		}
+	})
+
+	t.Run("fail with the quantile whose min is greater than max", func(t *testing.T) {
+		pm := NewParallel("", &parallelDBMock{
+			quantiles: []Quantile{
+				{Min: 1000, Max: 1, Count: 250},
+				{Min: 1001, Max: 1500, Count: 250},
+			},
+		}, 2)
+
+		var (
+			result []Partition
+			err    error
+		)
+		assert.NotPanics(t, func() { result, err = pm.Partition(Balanced) })
+		assert.Error(t, err)
+		assert.Empty(t, result)
+	})
+
+	t.Run("fail without QuantileDB", func(t *testing.T) {
+		pm := NewParallel("", &sortByDBMock{}, 4)
+
+		_, err := pm.Partition(Balanced)
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_HashBucket(t *testing.T) {
+	t.Run("divide into buckets", func(t *testing.T) {
+		pm := NewParallel("", &sortByDBMock{}, 4)
+
+		result, err := pm.Partition(HashBucket)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 4)
+		for i, r := range result {
+			assert.Equal(t, HashType, r.Type())
+			assert.Equal(t, int64(i), r.Min())
+			assert.Equal(t, int64(4), r.Max())
+		}
+	})
+
+	t.Run("fail without buckets", func(t *testing.T) {
+		pm := NewParallel("", &sortByDBMock{}, 0)
+
+		_, err := pm.Partition(HashBucket)
+
+		assert.Error(t, err)
+	})
+}
+
+func Test_Files(t *testing.T) {
+	dir := t.TempDir()
+
+	content := "id,title\n1,a\n2,bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb\n3,c\n4,d\n5,e"
+	path := filepath.Join(dir, "faqs.csv")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	t.Run("divide a file into byte ranges on line breaks", func(t *testing.T) {
+		pm := NewParallel(path, nil, 4)
+
+		result, err := pm.Partition(FileRanges)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 2) // the second and the third ranges are in the long record
+
+		start := int64(0)
+		for _, r := range result {
+			assert.Equal(t, ByteRangeType, r.Type())
+			assert.Equal(t, start, r.Min())
+			if r.Min() > 0 {
+				assert.Equal(t, byte('\n'), content[r.Min()-1])
+			}
+			start = r.Max()
+		}
+		assert.Equal(t, int64(len(content)), start)
+	})
+
+	t.Run("divide a glob into files", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "faqs2.csv"), []byte(content), 0644))
+
+		pm := NewParallel(filepath.Join(dir, "*.csv"), nil, 1)
+
+		result, err := pm.Partition(Files)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, 2)
+		assert.Equal(t, FileType, result[1].Type())
+		assert.Equal(t, int64(1), result[1].Min())
+		assert.Equal(t, int64(2), result[1].Max())
+		assert.Equal(t, "pCtx1-faqs2.csv", result[1].PartitionName())
+
+		file, err := FileOf(filepath.Join(dir, "*.csv"), result[1])
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "faqs2.csv"), file)
+
+		_, err = NewParallel(filepath.Join(dir, "*.json"), nil, 1).Partition(Files)
+		assert.Error(t, err)
+	})
+}
+
+func Test_SplitPartition(t *testing.T) {
+	t.Run("split only the unclaimed remainder", func(t *testing.T) {
+		sp := NewSplitPartition(RestorePartition("pCtx0", 1, 1000, 0, AutoIncrementIdType))
+
+		_, ok, err := sp.Split(10, nil)
+		assert.NoError(t, err)
+		assert.False(t, ok, "not started")
+
+		assert.Equal(t, int64(100), sp.Claim(100))
+		assert.Equal(t, int64(900), sp.Remaining())
+
+		sub, ok, err := sp.Split(10, func(max int64, sub Partition) error {
+			assert.Equal(t, int64(550), max)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "pCtx0-551", sub.PartitionName())
+		assert.Equal(t, int64(551), sub.Min())
+		assert.Equal(t, int64(1000), sub.Max())
+		assert.Equal(t, int64(550), sp.Max())
+
+		assert.Equal(t, int64(550), sp.Claim(600))
+		_, ok, _ = sp.Split(10, nil)
+		assert.False(t, ok, "nothing remains")
+	})
+
+	t.Run("keep the partition when commit fails", func(t *testing.T) {
+		sp := NewSplitPartition(RestorePartition("pCtx0", 1, 1000, 0, AutoIncrementIdType))
+		sp.Claim(100)
+
+		_, ok, err := sp.Split(10, func(max int64, sub Partition) error {
+			return assert.AnError
+		})
+		assert.Error(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, int64(1000), sp.Max())
+	})
+
+	t.Run("every id is claimed once while splitting", func(t *testing.T) {
+		var mu sync.Mutex
+		claimed := make(map[int64]int)
+
+		read := func(sp SplitPartition) {
+			for from := sp.Min(); ; {
+				to := sp.Claim(from + 9)
+				mu.Lock()
+				for id := from; id <= to; id++ {
+					claimed[id]++
+				}
+				mu.Unlock()
+				if to >= sp.Max() {
+					return
+				}
+				from = to + 1
+			}
+		}
+
+		root := NewSplitPartition(RestorePartition("pCtx0", 1, 10000, 0, AutoIncrementIdType))
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read(root)
+		}()
+
+		for i := 0; i < 20; i++ {
+			sub, ok, err := root.Split(5, nil)
+			assert.NoError(t, err)
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				read(NewSplitPartition(sub))
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, claimed, 10000)
+		for id, n := range claimed {
+			assert.Equal(t, 1, n, id)
+		}
+	})
+}
+
+type sortByDBMock struct{}
+
+func (m *sortByDBMock) GetSortBy(sourceName string) (Partition, error) {
+	return NewPartition(0, 0, 0), nil
+}
+
 type parallelDBMock struct {
 	mock.Mock
-	count int64
-	min   int64
-	max   int64
+	count      int64
+	min        int64
+	max        int64
+	boundaries []int64
+	minTime    time.Time
+	maxTime    time.Time
+	quantiles  []Quantile
 }
 
 func (m *parallelDBMock) GetSortBy(sourceName string) (Partition, error) {
 	return NewPartition(m.min, m.max, m.count), nil
 }
 
+func (m *parallelDBMock) GetBoundaryKeys(sourceName string, parallelSize int64) ([]int64, error) {
+	return m.boundaries, nil
+}
+
+func (m *parallelDBMock) GetTimeRange(sourceName string) (time.Time, time.Time, error) {
+	return m.minTime, m.maxTime, nil
+}
+
+func (m *parallelDBMock) GetQuantiles(sourceName string, parallelSize int64) ([]Quantile, error) {
+	return m.quantiles, nil
+}
+
 type mockSortableIDData struct {
 	limit  int64
 	offset int64
@@ -87,3 +430,24 @@ type mockAutoIncrementIDData struct {
 	min int64
 	max int64
 }
+
+func Test_ParallelTypeFuncName(t *testing.T) {
+	t.Run("name of partitioning strategy", func(t *testing.T) {
+		assert.Equal(t, "AutoIncrementId", AutoIncrementId.Name())
+		assert.Equal(t, "SortableId", SortableId.Name())
+		assert.Equal(t, "None", None.Name())
+		assert.Equal(t, "TimeSlices", TimeSlices.Name())
+		assert.Equal(t, "Balanced", Balanced.Name())
+		assert.Equal(t, "TimeInterval", TimeInterval(Day).Name())
+		assert.Equal(t, "FileRanges", FileRanges.Name())
+		assert.Equal(t, "Files", Files.Name())
+		assert.Equal(t, "HashBucket", HashBucket.Name())
+		assert.Equal(t, "SampledKey", SampledKey.Name())
+	})
+
+	t.Run("fail with empty ParallelTypeFunc", func(t *testing.T) {
+		_, err := NewParallel("", &parallelDBMock{}, 2).Partition(ParallelTypeFunc{})
+
+		assert.Error(t, err)
+	})
+}
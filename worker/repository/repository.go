@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+)
+
+type PartitionState struct {
+	Name       string
+	Min        int64
+	Max        int64
+	Count      int64
+	Type       int64
+	Checkpoint step.Checkpoint
+	Finished   bool
+}
+
+func NewPartitionState(p parallel.Partition) PartitionState {
+	return PartitionState{
+		Name:  p.PartitionName(),
+		Min:   p.Min(),
+		Max:   p.Max(),
+		Count: p.Count(),
+		Type:  p.Type(),
+	}
+}
+
+func (ps PartitionState) Partition() parallel.Partition {
+	return parallel.RestorePartition(ps.Name, ps.Min, ps.Max, ps.Count, ps.Type)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+)
+
+// JobRepository stores the partitions of a job and the checkpoint of each partition to restart the job
+type JobRepository interface {
+	// GetPartitions returns the partitions saved by the unfinished run of jobName. It returns empty if there is none
+	GetPartitions(ctx context.Context, jobName string) ([]PartitionState, error)
+	SavePartitions(ctx context.Context, jobName string, partitions []parallel.Partition) error
+	SaveCheckpoint(ctx context.Context, jobName, partitionName string, cp step.Checkpoint) error
+	FinishPartition(ctx context.Context, jobName, partitionName string) error
+	// SplitPartition shrinks partitionName to max and adds sub holding the rest of its range at once
+	SplitPartition(ctx context.Context, jobName, partitionName string, max int64, sub parallel.Partition) error
+	// FinishJob removes the saved run so that the next run of jobName starts from the beginning
+	FinishJob(ctx context.Context, jobName string) error
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+var errPartitionNotFound = errors.New("partition not found")
+
+type memoryJobRepository struct {
+	mu   sync.Mutex
+	jobs map[string][]PartitionState
+}
+
+// NewMemoryJobRepository returns JobRepository that keeps the states in memory. It is useful for tests
+func NewMemoryJobRepository() JobRepository {
+	return &memoryJobRepository{
+		jobs: make(map[string][]PartitionState),
+	}
+}
+
+func (mjr *memoryJobRepository) GetPartitions(ctx context.Context, jobName string) ([]PartitionState, error) {
+	mjr.mu.Lock()
+	defer mjr.mu.Unlock()
+
+	states := make([]PartitionState, len(mjr.jobs[jobName]))
+	copy(states, mjr.jobs[jobName])
+
+	return states, nil
+}
+
+func (mjr *memoryJobRepository) SavePartitions(ctx context.Context, jobName string, partitions []parallel.Partition) error {
+	mjr.mu.Lock()
+	defer mjr.mu.Unlock()
+
+	states := make([]PartitionState, 0, len(partitions))
+	for _, p := range partitions {
+		states = append(states, NewPartitionState(p))
+	}
+	mjr.jobs[jobName] = states
+
+	return nil
+}
+
+func (mjr *memoryJobRepository) SaveCheckpoint(ctx context.Context, jobName, partitionName string, cp step.Checkpoint) error {
+	return mjr.update(jobName, partitionName, func(ps *PartitionState) {
+		ps.Checkpoint = cp
+	})
+}
+
+func (mjr *memoryJobRepository) FinishPartition(ctx context.Context, jobName, partitionName string) error {
+	return mjr.update(jobName, partitionName, func(ps *PartitionState) {
+		ps.Finished = true
+	})
+}
+
+func (mjr *memoryJobRepository) SplitPartition(ctx context.Context, jobName, partitionName string, max int64, sub parallel.Partition) error {
+	op := er.GetOperator()
+
+	mjr.mu.Lock()
+	defer mjr.mu.Unlock()
+
+	states := mjr.jobs[jobName]
+	for i := range states {
+		if states[i].Name == partitionName {
+			states[i].Max = max
+			mjr.jobs[jobName] = append(states, NewPartitionState(sub))
+			return nil
+		}
+	}
+
+	return er.WrapOpAndKind(errPartitionNotFound, op, er.KindNotFound)
+}
+
+func (mjr *memoryJobRepository) FinishJob(ctx context.Context, jobName string) error {
+	mjr.mu.Lock()
+	defer mjr.mu.Unlock()
+
+	delete(mjr.jobs, jobName)
+
+	return nil
+}
+
+func (mjr *memoryJobRepository) update(jobName, partitionName string, f func(*PartitionState)) error {
+	op := er.GetOperator()
+
+	mjr.mu.Lock()
+	defer mjr.mu.Unlock()
+
+	states := mjr.jobs[jobName]
+	for i := range states {
+		if states[i].Name == partitionName {
+			f(&states[i])
+			return nil
+		}
+	}
+
+	return er.WrapOpAndKind(errPartitionNotFound, op, er.KindNotFound)
+}
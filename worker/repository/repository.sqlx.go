@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/dialect"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/jmoiron/sqlx"
+)
+
+const DefaultTableName = "batch_job_partition"
+
+// Schema returns DDL of the table used by the sqlx JobRepository. tableName is quoted by d
+func Schema(d dialect.Dialect, tableName string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	job_name       VARCHAR(255) NOT NULL,
+	partition_name VARCHAR(255) NOT NULL,
+	seq            BIGINT       NOT NULL,
+	min_value      BIGINT       NOT NULL,
+	max_value      BIGINT       NOT NULL,
+	row_count      BIGINT       NOT NULL,
+	partition_type BIGINT       NOT NULL,
+	checkpoint     TEXT         NOT NULL,
+	finished       SMALLINT     NOT NULL DEFAULT 0,
+	PRIMARY KEY (job_name, partition_name)
+)`, d.Quote(tableName))
+}
+
+type partitionRow struct {
+	JobName       string `db:"job_name"`
+	PartitionName string `db:"partition_name"`
+	Seq           int64  `db:"seq"`
+	MinValue      int64  `db:"min_value"`
+	MaxValue      int64  `db:"max_value"`
+	RowCount      int64  `db:"row_count"`
+	PartitionType int64  `db:"partition_type"`
+	Checkpoint    string `db:"checkpoint"`
+	Finished      int64  `db:"finished"`
+}
+
+type sqlxJobRepository struct {
+	db        *sqlx.DB
+	tableName string // quoted
+}
+
+// NewSqlxJobRepository returns JobRepository that stores the states in tableName. The table can be created with Schema
+// tableName ("schema.table" too) is quoted by the dialect of the driver of db
+func NewSqlxJobRepository(db *sqlx.DB, tableName string) JobRepository {
+	d, err := dialect.FromDriverName(db.DriverName())
+	if err != nil {
+		// the other drivers quote by ANSI double quotes like Postgres
+		d = dialect.Postgres
+	}
+
+	return &sqlxJobRepository{
+		db:        db,
+		tableName: d.Quote(tableName),
+	}
+}
+
+func (sjr *sqlxJobRepository) GetPartitions(ctx context.Context, jobName string) ([]PartitionState, error) {
+	op := er.GetOperator()
+
+	var rows []partitionRow
+
+	q := sjr.db.Rebind(fmt.Sprintf("SELECT * FROM %s WHERE job_name = ? ORDER BY seq", sjr.tableName))
+	if err := sjr.db.SelectContext(ctx, &rows, q, jobName); err != nil {
+		return nil, er.WrapOp(err, op)
+	}
+
+	states := make([]PartitionState, 0, len(rows))
+	for _, r := range rows {
+		var cp step.Checkpoint
+		if err := json.Unmarshal([]byte(r.Checkpoint), &cp); err != nil {
+			return nil, er.WrapOp(err, op)
+		}
+
+		states = append(states, PartitionState{
+			Name:       r.PartitionName,
+			Min:        r.MinValue,
+			Max:        r.MaxValue,
+			Count:      r.RowCount,
+			Type:       r.PartitionType,
+			Checkpoint: cp,
+			Finished:   r.Finished == 1,
+		})
+	}
+
+	return states, nil
+}
+
+func (sjr *sqlxJobRepository) SavePartitions(ctx context.Context, jobName string, partitions []parallel.Partition) error {
+	op := er.GetOperator()
+
+	tx, err := sjr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return er.WrapOp(err, op)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(fmt.Sprintf("DELETE FROM %s WHERE job_name = ?", sjr.tableName)), jobName); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	for i, p := range partitions {
+		if err := sjr.insert(ctx, tx, jobName, int64(i), p); err != nil {
+			return er.WrapOp(err, op)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+func (sjr *sqlxJobRepository) SaveCheckpoint(ctx context.Context, jobName, partitionName string, cp step.Checkpoint) error {
+	op := er.GetOperator()
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return sjr.update(ctx, op, "checkpoint = ?", string(b), jobName, partitionName)
+}
+
+func (sjr *sqlxJobRepository) FinishPartition(ctx context.Context, jobName, partitionName string) error {
+	op := er.GetOperator()
+
+	return sjr.update(ctx, op, "finished = ?", 1, jobName, partitionName)
+}
+
+func (sjr *sqlxJobRepository) SplitPartition(ctx context.Context, jobName, partitionName string, max int64, sub parallel.Partition) error {
+	op := er.GetOperator()
+
+	tx, err := sjr.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return er.WrapOp(err, op)
+	}
+	defer tx.Rollback()
+
+	q := tx.Rebind(fmt.Sprintf("UPDATE %s SET max_value = ? WHERE job_name = ? AND partition_name = ?", sjr.tableName))
+	res, err := tx.ExecContext(ctx, q, max, jobName, partitionName)
+	if err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := sjr.checkUpdated(ctx, tx, res, jobName, partitionName); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	var seq int64
+	q = tx.Rebind(fmt.Sprintf("SELECT COALESCE(MAX(seq), -1) + 1 FROM %s WHERE job_name = ?", sjr.tableName))
+	if err := tx.GetContext(ctx, &seq, q, jobName); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := sjr.insert(ctx, tx, jobName, seq, sub); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+func (sjr *sqlxJobRepository) insert(ctx context.Context, tx *sqlx.Tx, jobName string, seq int64, p parallel.Partition) error {
+	q := fmt.Sprintf(`INSERT INTO %s (job_name, partition_name, seq, min_value, max_value, row_count, partition_type, checkpoint, finished)
+VALUES (:job_name, :partition_name, :seq, :min_value, :max_value, :row_count, :partition_type, :checkpoint, :finished)`, sjr.tableName)
+
+	_, err := tx.NamedExecContext(ctx, q, partitionRow{
+		JobName:       jobName,
+		PartitionName: p.PartitionName(),
+		Seq:           seq,
+		MinValue:      p.Min(),
+		MaxValue:      p.Max(),
+		RowCount:      p.Count(),
+		PartitionType: p.Type(),
+		Checkpoint:    "{}",
+	})
+
+	return err
+}
+
+func (sjr *sqlxJobRepository) FinishJob(ctx context.Context, jobName string) error {
+	op := er.GetOperator()
+
+	q := sjr.db.Rebind(fmt.Sprintf("DELETE FROM %s WHERE job_name = ?", sjr.tableName))
+	if _, err := sjr.db.ExecContext(ctx, q, jobName); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+func (sjr *sqlxJobRepository) update(ctx context.Context, op, set string, value any, jobName, partitionName string) error {
+	q := sjr.db.Rebind(fmt.Sprintf("UPDATE %s SET %s WHERE job_name = ? AND partition_name = ?", sjr.tableName, set))
+
+	res, err := sjr.db.ExecContext(ctx, q, value, jobName, partitionName)
+	if err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := sjr.checkUpdated(ctx, sjr.db, res, jobName, partitionName); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+// checkUpdated returns er.KindNotFound error if the update of res found no partition named partitionName
+// MySQL doesn't count the rows updated with the same values, so the partition is looked up when no row is affected
+func (sjr *sqlxJobRepository) checkUpdated(ctx context.Context, queryer sqlx.QueryerContext, res sql.Result, jobName, partitionName string) error {
+	affected, err := res.RowsAffected()
+	if err != nil || affected > 0 {
+		// the driver not reporting the affected rows cannot be checked
+		return nil
+	}
+
+	var count int64
+
+	q := sjr.db.Rebind(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE job_name = ? AND partition_name = ?", sjr.tableName))
+	if err := sqlx.GetContext(ctx, queryer, &count, q, jobName, partitionName); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return er.WrapKind(errPartitionNotFound, er.KindNotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/dialect"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+// connMock is the database/sql driver recording the statements executed
+// The queries return the rows of result and the statements affect the rows of affected (1 if not set)
+type connMock struct {
+	queries  []string
+	args     [][]driver.Value
+	commits  int
+	result   func(query string) ([]string, [][]driver.Value)
+	affected func(query string) int64
+}
+
+func newDriverMock(driverName string) (*sqlx.DB, *connMock) {
+	conn := &connMock{}
+	return sqlx.NewDb(sql.OpenDB(conn), driverName), conn
+}
+
+func (c *connMock) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *connMock) Driver() driver.Driver                        { return nil }
+func (c *connMock) Close() error                                 { return nil }
+func (c *connMock) Begin() (driver.Tx, error)                    { return c, nil }
+func (c *connMock) Rollback() error                              { return nil }
+
+func (c *connMock) Commit() error {
+	c.commits++
+	return nil
+}
+
+func (c *connMock) Prepare(query string) (driver.Stmt, error) {
+	return &stmtMock{conn: c, query: query}, nil
+}
+
+type stmtMock struct {
+	conn  *connMock
+	query string
+}
+
+func (s *stmtMock) Close() error  { return nil }
+func (s *stmtMock) NumInput() int { return -1 }
+
+func (s *stmtMock) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.queries = append(s.conn.queries, s.query)
+	s.conn.args = append(s.conn.args, args)
+
+	if s.conn.affected != nil {
+		return driver.RowsAffected(s.conn.affected(s.query)), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stmtMock) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.queries = append(s.conn.queries, s.query)
+	s.conn.args = append(s.conn.args, args)
+
+	columns, rows := s.conn.result(s.query)
+	return &rowsMock{columns: columns, rows: rows}, nil
+}
+
+type rowsMock struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rowsMock) Columns() []string { return r.columns }
+func (r *rowsMock) Close() error      { return nil }
+
+func (r *rowsMock) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func Test_SqlxJobRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("restore the partitions with checkpoints", func(t *testing.T) {
+		db, conn := newDriverMock("postgres")
+		conn.result = func(string) ([]string, [][]driver.Value) {
+			return []string{"job_name", "partition_name", "seq", "min_value", "max_value", "row_count", "partition_type", "checkpoint", "finished"},
+				[][]driver.Value{
+					{"faqs", "pCtx0", int64(0), int64(1), int64(100), int64(0), parallel.AutoIncrementIdType, "{}", int64(1)},
+					{"faqs", "pCtx1", int64(1), int64(101), int64(200), int64(0), parallel.AutoIncrementIdType, `{"page":2,"offset":30}`, int64(0)},
+				}
+		}
+
+		states, err := NewSqlxJobRepository(db, DefaultTableName).GetPartitions(ctx, "faqs")
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{`SELECT * FROM "batch_job_partition" WHERE job_name = $1 ORDER BY seq`}, conn.queries)
+		assert.Len(t, states, 2)
+		assert.True(t, states[0].Finished)
+		assert.False(t, states[1].Finished)
+		assert.Equal(t, step.Checkpoint{Page: 2, Offset: 30}, states[1].Checkpoint)
+		assert.Equal(t, int64(101), states[1].Partition().Min())
+		assert.Equal(t, "pCtx1", states[1].Partition().PartitionName())
+	})
+
+	t.Run("save the partitions and the states", func(t *testing.T) {
+		db, conn := newDriverMock("mysql")
+		repo := NewSqlxJobRepository(db, "batch.job_partition")
+
+		assert.NoError(t, repo.SavePartitions(ctx, "faqs", []parallel.Partition{
+			parallel.RestorePartition("pCtx0", 1, 100, 0, parallel.AutoIncrementIdType),
+			parallel.RestorePartition("pCtx1", 101, 200, 0, parallel.AutoIncrementIdType),
+		}))
+		assert.NoError(t, repo.SaveCheckpoint(ctx, "faqs", "pCtx1", step.Checkpoint{Page: 2, Offset: 30}))
+		assert.NoError(t, repo.FinishPartition(ctx, "faqs", "pCtx0"))
+		assert.NoError(t, repo.FinishJob(ctx, "faqs"))
+
+		assert.Equal(t, "DELETE FROM `batch`.`job_partition` WHERE job_name = ?", conn.queries[0])
+		assert.True(t, strings.HasPrefix(conn.queries[1], "INSERT INTO `batch`.`job_partition` (job_name, partition_name, seq,"))
+		assert.Equal(t, []driver.Value{"faqs", "pCtx1", int64(1), int64(101), int64(200), int64(0), parallel.AutoIncrementIdType, "{}", int64(0)}, conn.args[2])
+		assert.Equal(t, 1, conn.commits)
+
+		assert.Equal(t, "UPDATE `batch`.`job_partition` SET checkpoint = ? WHERE job_name = ? AND partition_name = ?", conn.queries[3])
+		assert.Equal(t, []driver.Value{`{"page":2,"offset":30}`, "faqs", "pCtx1"}, conn.args[3])
+		assert.Equal(t, []driver.Value{int64(1), "faqs", "pCtx0"}, conn.args[4])
+		assert.Equal(t, "DELETE FROM `batch`.`job_partition` WHERE job_name = ?", conn.queries[5])
+	})
+
+	t.Run("split the partition after the last seq", func(t *testing.T) {
+		db, conn := newDriverMock("sqlite3")
+		conn.result = func(string) ([]string, [][]driver.Value) {
+			return []string{"seq"}, [][]driver.Value{{int64(2)}}
+		}
+
+		sub := parallel.RestorePartition("pCtx0-51", 51, 100, 0, parallel.AutoIncrementIdType)
+		assert.NoError(t, NewSqlxJobRepository(db, DefaultTableName).SplitPartition(ctx, "faqs", "pCtx0", 50, sub))
+
+		assert.Equal(t, `UPDATE "batch_job_partition" SET max_value = ? WHERE job_name = ? AND partition_name = ?`, conn.queries[0])
+		assert.Equal(t, []driver.Value{int64(50), "faqs", "pCtx0"}, conn.args[0])
+		assert.Equal(t, []driver.Value{"faqs", "pCtx0-51", int64(2), int64(51), int64(100), int64(0), parallel.AutoIncrementIdType, "{}", int64(0)}, conn.args[2])
+		assert.Equal(t, 1, conn.commits)
+	})
+
+	t.Run("quote the table name", func(t *testing.T) {
+		db, conn := newDriverMock("postgres")
+		assert.NoError(t, NewSqlxJobRepository(db, `jobs"; DROP TABLE faqs; --`).FinishJob(ctx, "faqs"))
+
+		assert.Equal(t, []string{`DELETE FROM "jobs""; DROP TABLE faqs; --" WHERE job_name = $1`}, conn.queries)
+		assert.True(t, strings.HasPrefix(Schema(dialect.MySQL, DefaultTableName), "CREATE TABLE IF NOT EXISTS `batch_job_partition` ("))
+	})
+	t.Run("fail to update the partition not saved", func(t *testing.T) {
+		db, conn := newDriverMock("mysql")
+		conn.affected = func(string) int64 { return 0 }
+		conn.result = func(string) ([]string, [][]driver.Value) {
+			return []string{"COUNT(*)"}, [][]driver.Value{{int64(0)}}
+		}
+		repo := NewSqlxJobRepository(db, DefaultTableName)
+
+		err := repo.SaveCheckpoint(ctx, "faqs", "pCtx9", step.Checkpoint{Page: 2})
+		assert.True(t, er.IsKind(err, er.KindNotFound))
+		assert.Equal(t, "SELECT COUNT(*) FROM `batch_job_partition` WHERE job_name = ? AND partition_name = ?", conn.queries[1])
+		assert.Equal(t, []driver.Value{"faqs", "pCtx9"}, conn.args[1])
+
+		err = repo.FinishPartition(ctx, "faqs", "pCtx9")
+		assert.True(t, er.IsKind(err, er.KindNotFound))
+
+		err = repo.SplitPartition(ctx, "faqs", "pCtx9", 50, parallel.RestorePartition("pCtx9-51", 51, 100, 0, parallel.AutoIncrementIdType))
+		assert.True(t, er.IsKind(err, er.KindNotFound))
+		assert.Equal(t, 0, conn.commits)
+	})
+
+	t.Run("update the partition with the same values", func(t *testing.T) {
+		db, conn := newDriverMock("mysql")
+		// MySQL affects no row when the values don't change
+		conn.affected = func(string) int64 { return 0 }
+		conn.result = func(string) ([]string, [][]driver.Value) {
+			return []string{"COUNT(*)"}, [][]driver.Value{{int64(1)}}
+		}
+
+		assert.NoError(t, NewSqlxJobRepository(db, DefaultTableName).FinishPartition(ctx, "faqs", "pCtx0"))
+	})
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_MemoryJobRepository(t *testing.T) {
+	t.Run("restore partitions with checkpoints until the job finishes", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMemoryJobRepository()
+
+		err := repo.SavePartitions(ctx, "faqs", []parallel.Partition{
+			parallel.RestorePartition("pCtx0", 1, 100, 0, parallel.AutoIncrementIdType),
+			parallel.RestorePartition("pCtx1", 101, 200, 0, parallel.AutoIncrementIdType),
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.FinishPartition(ctx, "faqs", "pCtx0"))
+		assert.NoError(t, repo.SaveCheckpoint(ctx, "faqs", "pCtx1", step.Checkpoint{Page: 2, Offset: 30}))
+		assert.Error(t, repo.SaveCheckpoint(ctx, "faqs", "pCtx9", step.Checkpoint{}))
+
+		states, err := repo.GetPartitions(ctx, "faqs")
+		assert.NoError(t, err)
+		assert.Len(t, states, 2)
+		assert.True(t, states[0].Finished)
+		assert.False(t, states[1].Finished)
+		assert.Equal(t, step.Checkpoint{Page: 2, Offset: 30}, states[1].Checkpoint)
+		assert.Equal(t, int64(101), states[1].Partition().Min())
+		assert.Equal(t, "pCtx1", states[1].Partition().PartitionName())
+
+		assert.NoError(t, repo.FinishJob(ctx, "faqs"))
+
+		states, err = repo.GetPartitions(ctx, "faqs")
+		assert.NoError(t, err)
+		assert.Empty(t, states)
+	})
+
+	t.Run("split partition keeps both halves", func(t *testing.T) {
+		ctx := context.Background()
+		repo := NewMemoryJobRepository()
+
+		assert.NoError(t, repo.SavePartitions(ctx, "faqs", []parallel.Partition{
+			parallel.RestorePartition("pCtx0", 1, 100, 0, parallel.AutoIncrementIdType),
+		}))
+
+		sub := parallel.RestorePartition("pCtx0-51", 51, 100, 0, parallel.AutoIncrementIdType)
+		assert.NoError(t, repo.SplitPartition(ctx, "faqs", "pCtx0", 50, sub))
+		assert.Error(t, repo.SplitPartition(ctx, "faqs", "pCtx9", 50, sub))
+
+		states, err := repo.GetPartitions(ctx, "faqs")
+		assert.NoError(t, err)
+		assert.Len(t, states, 2)
+		assert.Equal(t, int64(50), states[0].Max)
+		assert.Equal(t, "pCtx0-51", states[1].Name)
+		assert.Equal(t, int64(51), states[1].Min)
+	})
+}
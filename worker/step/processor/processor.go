@@ -18,7 +18,7 @@ func (p *processor[T, R, J]) Process(item T, param *J, pCtx parallel.Partition)
 
 	refineItem, err := item.ToModel(param)
 	if err != nil {
-		return nil, er.WrapOp(err, op)
+		return nil, er.WrapKindIfNotSet(er.WrapOp(err, op), er.KindInvalidItem)
 	}
 
 	return refineItem, nil
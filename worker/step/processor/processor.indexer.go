@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+)
+
+type indexerProcessor[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]] struct {
+	countryCode string
+}
+
+// NewIndexerProcessor returns Processor that passes countryCode to the param implementing step.CountryCodeParam before processing
+// The param is copied for every item, and the param returned by step.ProcessorParam is not changed
+func NewIndexerProcessor[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](countryCode string) step.Processor[T, R, J] {
+	return &indexerProcessor[T, R, J]{
+		countryCode: countryCode,
+	}
+}
+
+func (p *indexerProcessor[T, R, J]) Process(item T, param *J, pCtx parallel.Partition) (*R, error) {
+	op := er.GetOperator()
+
+	// the param can be shared by the partitions and the processors of step.Pipeline, so set the country code to a copy
+	if _, ok := any(param).(step.CountryCodeParam); ok && param != nil {
+		local := *param
+		any(&local).(step.CountryCodeParam).SetCountryCode(p.countryCode)
+		param = &local
+	}
+
+	refineItem, err := item.ToModel(param)
+	if err != nil {
+		return nil, er.WrapKindIfNotSet(er.WrapOp(err, op), er.KindInvalidItem)
+	}
+
+	return refineItem, nil
+}
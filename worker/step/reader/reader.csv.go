@@ -0,0 +1,186 @@
+package reader
+
+import (
+	"context"
+	"encoding/csv"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/mapper"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+)
+
+// CsvOption is the option of NewCsvFileReader
+type CsvOption struct {
+	Comma rune // field delimiter. ',' if not set
+	// NoHeader maps the columns to the fields of T in order (see mapper.Fields.Names). The columns are mapped by the header otherwise
+	NoHeader bool
+}
+
+type csvFileReader[T any] struct {
+	source     string // file path or glob pattern of parallel.Files
+	opt        CsvOption
+	partition  parallel.Partition
+	file       *os.File
+	csv        *csv.Reader
+	fields     []mapper.Field // fields of the columns in T
+	columns    []int          // index of the column of each field in the record
+	ptrs       []any
+	consumed   int64 // records read
+	skip       int64 // records to skip after Seek
+	readStatus status
+}
+
+// NewCsvFileReader returns the reader scanning the CSV records of source into T by the db tags. see mapper.Tag
+// The columns of the header not in T are ignored. Partition the file by parallel.FileRanges or the glob by parallel.Files
+func NewCsvFileReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](source string, opt CsvOption) step.Reader[T, R, J] {
+	return NewCursorReader[T, R, J](&csvFileReader[T]{
+		source: source,
+		opt:    opt,
+	})
+}
+
+// Open returns the cursor of the records of the partition. see step.Source
+func (cfr *csvFileReader[T]) Open(_ context.Context, partCtx parallel.Partition) (step.Cursor[T], error) {
+	return &csvFileReader[T]{
+		source:     cfr.source,
+		opt:        cfr.opt,
+		partition:  partCtx,
+		readStatus: statusReady,
+	}, nil
+}
+
+func (cfr *csvFileReader[T]) Next(_ context.Context) (*T, bool, error) {
+	op := er.GetOperator()
+
+	switch cfr.readStatus {
+	case statusReady:
+		if err := cfr.open(cfr.partition); err != nil {
+			log.Err(err).Msgf("file: %s", cfr.source)
+			return nil, false, er.WrapOp(err, op)
+		}
+	case statusFinish:
+		return nil, true, nil
+	}
+
+	record, err := cfr.csv.Read()
+	if err == io.EOF {
+		cfr.Close()
+		cfr.readStatus = statusFinish
+		return nil, true, nil
+	}
+
+	// a record that failed to parse is consumed too
+	cfr.consumed++
+
+	if err != nil {
+		log.Err(err).Msgf("parse error")
+		return nil, false, er.WrapOpAndKind(err, op, er.KindInvalidItem)
+	}
+
+	var item T
+	if err := cfr.scan(record, &item); err != nil {
+		log.Err(err).Msgf("scan error")
+		return nil, false, er.WrapOpAndKind(err, op, er.KindInvalidItem)
+	}
+
+	return &item, false, nil
+}
+
+func (cfr *csvFileReader[T]) open(partCtx parallel.Partition) error {
+	f, r, err := openFile(cfr.source, partCtx)
+	if err != nil {
+		return err
+	}
+	cfr.file = f
+	cfr.csv = cfr.newCsv(r)
+
+	mapped := mapper.For[T]()
+
+	var columns []string
+	switch {
+	case cfr.opt.NoHeader:
+		columns = mapped.Names()
+	case partCtx.Type() == parallel.ByteRangeType && partCtx.Min() > 0:
+		// the header is the first record of the file, not of the range
+		if columns, err = cfr.newCsv(io.NewSectionReader(f, 0, partCtx.Min())).Read(); err != nil {
+			return errors.Wrap(err, "cannot read header")
+		}
+	default:
+		if columns, err = cfr.csv.Read(); err != nil && err != io.EOF {
+			return errors.Wrap(err, "cannot read header")
+		}
+	}
+
+	cfr.fields, cfr.columns = nil, nil
+	for i, c := range columns {
+		if field, ok := mapped.Field(c); ok {
+			cfr.fields = append(cfr.fields, field)
+			cfr.columns = append(cfr.columns, i)
+		}
+	}
+
+	for ; cfr.skip > 0; cfr.skip-- {
+		if _, err := cfr.csv.Read(); err == io.EOF {
+			break
+		}
+		cfr.consumed++
+	}
+	cfr.skip = 0
+
+	cfr.readStatus = statusDoing
+	return nil
+}
+
+func (cfr *csvFileReader[T]) newCsv(r io.Reader) *csv.Reader {
+	c := csv.NewReader(r)
+	if cfr.opt.Comma != 0 {
+		c.Comma = cfr.opt.Comma
+	}
+	c.FieldsPerRecord = -1
+	c.ReuseRecord = true
+	return c
+}
+
+func (cfr *csvFileReader[T]) scan(record []string, item *T) error {
+	ptrs, err := mapper.For[T]().Pointers(item, cfr.fields, cfr.ptrs[:0])
+	if err != nil {
+		return err
+	}
+	cfr.ptrs = ptrs
+
+	for i, column := range cfr.columns {
+		// the short record leaves the fields zero
+		if column >= len(record) {
+			continue
+		}
+
+		if err := mapper.Parse(ptrs[i], record[column]); err != nil {
+			return errors.Wrapf(err, "column [%s]", cfr.fields[i].Name)
+		}
+	}
+
+	return nil
+}
+
+func (cfr *csvFileReader[T]) Close() error {
+	if cfr.file == nil {
+		return nil
+	}
+
+	f := cfr.file
+	cfr.file = nil
+
+	return f.Close()
+}
+
+func (cfr *csvFileReader[T]) Position() step.Checkpoint {
+	return step.Checkpoint{Offset: cfr.consumed + cfr.skip}
+}
+
+func (cfr *csvFileReader[T]) Seek(cp step.Checkpoint) {
+	cfr.skip = cp.Offset
+}
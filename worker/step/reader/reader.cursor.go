@@ -0,0 +1,130 @@
+package reader
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/rs/zerolog/log"
+)
+
+// cursorReader is Reader of the cursor that step.Source opens at the first Read of a partition
+type cursorReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]] struct {
+	source   step.Source[T]
+	cursor   step.Cursor[T]
+	cp       step.Checkpoint  // position to resume from
+	consumed int64            // items and errors read by the cursor not implementing step.Positioner
+	closed   *step.Checkpoint // position of the closed cursor implementing step.Positioner
+	done     bool
+}
+
+// NewCursorReader returns Reader of source. It is Positioner whether the cursor is or not, and the step closes the cursor when the partition ends
+func NewCursorReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](source step.Source[T]) step.Reader[T, R, J] {
+	return &cursorReader[T, R, J]{source: source}
+}
+
+func (cr *cursorReader[T, R, J]) New() step.Reader[T, R, J] {
+	return &cursorReader[T, R, J]{source: cr.source}
+}
+
+func (cr *cursorReader[T, R, J]) Read(ctx context.Context, partCtx parallel.Partition) (*T, bool, error) {
+	op := er.GetOperator()
+
+	if cr.done {
+		return nil, true, nil
+	}
+
+	if cr.cursor == nil {
+		if err := cr.open(ctx, partCtx); err != nil {
+			return nil, false, er.WrapOp(err, op)
+		}
+		if cr.done {
+			return nil, true, nil
+		}
+	}
+
+	item, done, err := cr.cursor.Next(ctx)
+	if done {
+		cr.done = true
+		if err := cr.Close(); err != nil {
+			log.Err(err).Msgf("[%s] failed to close cursor", partCtx.PartitionName())
+		}
+		return nil, true, nil
+	}
+
+	if item != nil || err != nil {
+		cr.consumed++
+	}
+
+	return item, false, err
+}
+
+func (cr *cursorReader[T, R, J]) open(ctx context.Context, partCtx parallel.Partition) error {
+	cursor, err := cr.source.Open(ctx, partCtx)
+	if err != nil {
+		return err
+	}
+	cr.cursor = cursor
+
+	if cr.cp == (step.Checkpoint{}) {
+		return nil
+	}
+
+	if positioner, ok := cursor.(step.Positioner); ok {
+		positioner.Seek(cr.cp)
+		return nil
+	}
+
+	// the items are read in the same order again
+	for cr.consumed < cr.cp.Offset {
+		item, done, err := cursor.Next(ctx)
+		if done {
+			cr.done = true
+			return cr.Close()
+		}
+		if item != nil || err != nil {
+			cr.consumed++
+		}
+	}
+
+	return nil
+}
+
+// Close closes the cursor of the partition. The reader returns done after Close
+func (cr *cursorReader[T, R, J]) Close() error {
+	if cr.cursor == nil {
+		return nil
+	}
+
+	cursor := cr.cursor
+	cr.cursor = nil
+	cr.done = true
+
+	if positioner, ok := cursor.(step.Positioner); ok {
+		pos := positioner.Position()
+		cr.closed = &pos
+	}
+
+	return cursor.Close()
+}
+
+func (cr *cursorReader[T, R, J]) Position() step.Checkpoint {
+	if positioner, ok := cr.cursor.(step.Positioner); ok {
+		return positioner.Position()
+	}
+
+	if cr.closed != nil {
+		return *cr.closed
+	}
+
+	// not opened yet
+	if cr.cursor == nil && !cr.done {
+		return cr.cp
+	}
+
+	return step.Checkpoint{Offset: cr.consumed}
+}
+
+func (cr *cursorReader[T, R, J]) Seek(cp step.Checkpoint) {
+	cr.cp = cp
+}
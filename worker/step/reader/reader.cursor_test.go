@@ -0,0 +1,81 @@
+package reader
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type sourceMock struct {
+	items  []int64
+	opened int
+	closed int
+}
+
+func (s *sourceMock) Open(context.Context, parallel.Partition) (step.Cursor[faqRow], error) {
+	s.opened++
+	return &cursorMock{source: s}, nil
+}
+
+type cursorMock struct {
+	source *sourceMock
+	next   int
+}
+
+func (c *cursorMock) Next(context.Context) (*faqRow, bool, error) {
+	if c.next >= len(c.source.items) {
+		return nil, true, nil
+	}
+
+	id := c.source.items[c.next]
+	c.next++
+
+	if id < 0 {
+		return nil, false, errors.New("invalid item")
+	}
+	return &faqRow{Id: id}, false, nil
+}
+
+func (c *cursorMock) Close() error {
+	c.source.closed++
+	return nil
+}
+
+func Test_CursorReader(t *testing.T) {
+	t.Run("open the cursor lazily and close it at the end", func(t *testing.T) {
+		source := &sourceMock{items: []int64{1, -1, 2}}
+		r := NewCursorReader[faqRow, faqRow, step.EmptyDocProcessorParamType](source).New()
+		assert.Zero(t, source.opened)
+
+		ids, failed := readAll(t, r, parallel.EmptyPartition)
+		assert.Equal(t, []int64{1, 2}, ids)
+		assert.Equal(t, 1, failed)
+		assert.Equal(t, 1, source.opened)
+		assert.Equal(t, 1, source.closed)
+		assert.Equal(t, step.Checkpoint{Offset: 3}, r.(step.Positioner).Position())
+	})
+
+	t.Run("skip the items before the checkpoint", func(t *testing.T) {
+		source := &sourceMock{items: []int64{1, 2, 3}}
+		r := NewCursorReader[faqRow, faqRow, step.EmptyDocProcessorParamType](source).New()
+		r.(step.Positioner).Seek(step.Checkpoint{Offset: 2})
+		assert.Equal(t, step.Checkpoint{Offset: 2}, r.(step.Positioner).Position())
+
+		ids, _ := readAll(t, r, parallel.EmptyPartition)
+		assert.Equal(t, []int64{3}, ids)
+	})
+
+	t.Run("close the cursor of the failed partition", func(t *testing.T) {
+		source := &sourceMock{items: []int64{1, 2}}
+		r := NewCursorReader[faqRow, faqRow, step.EmptyDocProcessorParamType](source).New()
+
+		_, _, err := r.Read(context.Background(), parallel.EmptyPartition)
+		assert.NoError(t, err)
+		assert.NoError(t, r.(interface{ Close() error }).Close())
+		assert.Equal(t, 1, source.closed)
+		assert.Equal(t, step.Checkpoint{Offset: 1}, r.(step.Positioner).Position())
+	})
+}
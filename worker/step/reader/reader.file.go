@@ -0,0 +1,33 @@
+package reader
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"io"
+	"os"
+)
+
+// openFile opens the records of the partition in the file source
+// parallel.ByteRangeType reads [Min, Max) bytes of the file, parallel.FileType the file of parallel.FileOf the glob source,
+// and the other partitions (example. parallel.None) the whole file
+func openFile(source string, partCtx parallel.Partition) (*os.File, io.Reader, error) {
+	path := source
+
+	if partCtx.Type() == parallel.FileType {
+		file, err := parallel.FileOf(source, partCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+		path = file
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if partCtx.Type() == parallel.ByteRangeType {
+		return f, io.NewSectionReader(f, partCtx.Min(), partCtx.Max()-partCtx.Min()), nil
+	}
+
+	return f, f, nil
+}
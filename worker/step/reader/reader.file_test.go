@@ -0,0 +1,157 @@
+package reader
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type faqRow struct {
+	Id        int64      `db:"id" json:"id"`
+	Title     string     `db:"title" json:"title"`
+	Score     *float64   `db:"score" json:"score"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at"`
+}
+
+func (f faqRow) ToModel(*step.EmptyDocProcessorParamType) (*faqRow, error) {
+	return &f, nil
+}
+
+type fileReader = step.Reader[faqRow, faqRow, step.EmptyDocProcessorParamType]
+
+func readAll(t *testing.T, r fileReader, p parallel.Partition) ([]int64, int) {
+	var ids []int64
+	var failed int
+
+	for {
+		item, done, err := r.Read(context.Background(), p)
+		if err != nil {
+			failed++
+			continue
+		}
+		if done {
+			return ids, failed
+		}
+		if item != nil {
+			ids = append(ids, item.Id)
+		}
+	}
+}
+
+func Test_CsvFileReader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "faqs.csv")
+
+	content := "title,id,memo,score,created_at\n" +
+		"a,1,x,0.5,2024-01-02T03:04:05Z\n" +
+		"\"b,\"\"quoted\"\"\",2,y,,2024-01-02T03:04:05Z\n" +
+		"c,3,z,1,2024-01-02T03:04:05Z\n" +
+		"d,four,w,1,2024-01-02T03:04:05Z\n" +
+		"e,5,v,1,2024-01-02T03:04:05Z\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	t.Run("scan records by the header", func(t *testing.T) {
+		r := NewCsvFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](path, CsvOption{}).New()
+
+		item, done, err := r.Read(context.Background(), parallel.EmptyPartition)
+		assert.NoError(t, err)
+		assert.False(t, done)
+		assert.Equal(t, faqRow{Id: 1, Title: "a", Score: item.Score, CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, *item)
+		assert.Equal(t, 0.5, *item.Score)
+
+		item, _, err = r.Read(context.Background(), parallel.EmptyPartition)
+		assert.NoError(t, err)
+		assert.Equal(t, `b,"quoted"`, item.Title)
+		assert.Nil(t, item.Score)
+
+		ids, failed := readAll(t, r, parallel.EmptyPartition)
+		assert.Equal(t, []int64{3, 5}, ids)
+		assert.Equal(t, 1, failed)
+	})
+
+	t.Run("read the byte ranges with the header of the file", func(t *testing.T) {
+		result, err := parallel.NewParallel(path, nil, 3).Partition(parallel.FileRanges)
+		assert.NoError(t, err)
+
+		var ids []int64
+		for _, p := range result {
+			read, _ := readAll(t, NewCsvFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](path, CsvOption{}).New(), p)
+			ids = append(ids, read...)
+		}
+		assert.Equal(t, []int64{1, 2, 3, 5}, ids)
+	})
+
+	t.Run("resume from the checkpoint", func(t *testing.T) {
+		r := NewCsvFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](path, CsvOption{}).New()
+		r.(step.Positioner).Seek(step.Checkpoint{Offset: 2})
+
+		ids, _ := readAll(t, r, parallel.EmptyPartition)
+		assert.Equal(t, []int64{3, 5}, ids)
+		assert.Equal(t, step.Checkpoint{Offset: 5}, r.(step.Positioner).Position())
+	})
+
+	t.Run("map the columns by the fields without a header", func(t *testing.T) {
+		noHeader := filepath.Join(dir, "faqs.tsv")
+		assert.NoError(t, os.WriteFile(noHeader, []byte("7\tg\n8\th\n"), 0644))
+
+		r := NewCsvFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](noHeader, CsvOption{Comma: '\t', NoHeader: true}).New()
+		ids, failed := readAll(t, r, parallel.EmptyPartition)
+		assert.Equal(t, []int64{7, 8}, ids)
+		assert.Zero(t, failed)
+	})
+}
+
+func Test_NdjsonFileReader(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "faqs-1.json"), []byte("{\"id\":1,\"title\":\"a\"}\n\n{\"id\":2}\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "faqs-2.json"), []byte("{\"id\":3}\n{\"id\":\n{\"id\":4}"), 0644))
+
+	t.Run("read a partition per file of the glob", func(t *testing.T) {
+		glob := filepath.Join(dir, "faqs-*.json")
+
+		result, err := parallel.NewParallel(glob, nil, 1).Partition(parallel.Files)
+		assert.NoError(t, err)
+
+		ids, failed := readAll(t, NewNdjsonFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](glob).New(), result[0])
+		assert.Equal(t, []int64{1, 2}, ids)
+		assert.Zero(t, failed)
+
+		ids, failed = readAll(t, NewNdjsonFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](glob).New(), result[1])
+		assert.Equal(t, []int64{3, 4}, ids)
+		assert.Equal(t, 1, failed)
+	})
+
+	t.Run("fail when the files changed after partitioning", func(t *testing.T) {
+		r := NewNdjsonFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](filepath.Join(dir, "faqs-*.json")).New()
+
+		_, _, err := r.Read(context.Background(), parallel.RestorePartition("pCtx2", 2, 3, 0, parallel.FileType))
+		assert.Error(t, err)
+	})
+
+	t.Run("fail when another file has the index of the partition", func(t *testing.T) {
+		dir := t.TempDir()
+		glob := filepath.Join(dir, "faqs-*.json")
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "faqs-1.json"), []byte("{\"id\":1}\n"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "faqs-2.json"), []byte("{\"id\":2}\n"), 0644))
+
+		result, err := parallel.NewParallel(glob, nil, 1).Partition(parallel.Files)
+		assert.NoError(t, err)
+
+		// the same number of files, but faqs-2.json has the index of faqs-1.json before the restart
+		assert.NoError(t, os.Remove(filepath.Join(dir, "faqs-1.json")))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "faqs-3.json"), []byte("{\"id\":3}\n"), 0644))
+
+		for _, p := range result {
+			restored := parallel.RestorePartition(p.PartitionName(), p.Min(), p.Max(), p.Count(), p.Type())
+			_, _, err = NewNdjsonFileReader[faqRow, faqRow, step.EmptyDocProcessorParamType](glob).New().Read(context.Background(), restored)
+			assert.Error(t, err, p.PartitionName())
+		}
+	})
+}
@@ -9,35 +9,41 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-type fullDocReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]] struct {
-	queryString string // need to set format "SELECT * FROM faqs WHERE faqs.id >= %d and fqs.id < %d"
+type fullDocReader[T any] struct {
+	queryString string // can bind the partition as "SELECT * FROM faqs WHERE faqs.id >= :from AND faqs.id <= :to"
 	docReaderDB step.ReaderDB
+	partition   parallel.Partition
+	stmt        *statement
 	rows        *sqlx.Rows
+	scanner     scanner[T]
+	consumed    int64 // items read
+	skip        int64 // items to skip after Seek
 	readStatus  status
 }
 
 func NewFullDocReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](queryString string, db step.ReaderDB) step.Reader[T, R, J] {
-	return &fullDocReader[T, R, J]{
+	return NewCursorReader[T, R, J](&fullDocReader[T]{
 		queryString: queryString,
 		docReaderDB: db,
-		readStatus:  statusReady,
-	}
+	})
 }
 
-func (fdr *fullDocReader[T, R, J]) New() step.Reader[T, R, J] {
-	return &fullDocReader[T, R, J]{
+// Open returns the cursor of the rows of the partition. see step.Source
+func (fdr *fullDocReader[T]) Open(_ context.Context, partCtx parallel.Partition) (step.Cursor[T], error) {
+	return &fullDocReader[T]{
 		queryString: fdr.queryString,
 		docReaderDB: fdr.docReaderDB,
-		readStatus:  fdr.readStatus,
-	}
+		partition:   partCtx,
+		readStatus:  statusReady,
+	}, nil
 }
 
-func (fdr *fullDocReader[T, R, J]) Read(ctx context.Context, partCtx parallel.Partition) (*T, bool, error) {
+func (fdr *fullDocReader[T]) Next(ctx context.Context) (*T, bool, error) {
 	op := er.GetOperator()
 
 	if fdr.readStatus == statusReady {
 
-		if err := fdr.read(ctx); err != nil {
+		if err := fdr.read(ctx, fdr.partition); err != nil {
 			return nil, false, er.WrapOp(err, op)
 		}
 
@@ -46,30 +52,52 @@ func (fdr *fullDocReader[T, R, J]) Read(ctx context.Context, partCtx parallel.Pa
 	return fdr.getItem()
 }
 
-func (fdr *fullDocReader[T, R, J]) getItem() (*T, bool, error) {
+func (fdr *fullDocReader[T]) getItem() (*T, bool, error) {
 	op := er.GetOperator()
 
 	hasNext := fdr.rows.Next()
 	if !hasNext {
-		fdr.rows.Close()
+		fdr.Close()
+		if err := fdr.rows.Err(); err != nil {
+			return nil, false, er.WrapOp(err, op)
+		}
 		fdr.readStatus = statusFinish
 		return nil, true, nil
 	}
 
+	// a row that failed to scan is consumed too
+	fdr.consumed++
+
 	var item T
-	if err := fdr.rows.StructScan(&item); err != nil {
+	if err := fdr.scanner.scan(fdr.rows, &item); err != nil {
 		log.Err(err).Msgf("scan error")
-		return nil, false, er.WrapOp(err, op)
+		return nil, false, er.WrapOpAndKind(err, op, er.KindInvalidItem)
 	}
 
 	return &item, false, nil
 
 }
 
-func (fdr *fullDocReader[T, R, J]) read(ctx context.Context) error {
+func (fdr *fullDocReader[T]) read(ctx context.Context, partCtx parallel.Partition) error {
 	op := er.GetOperator()
 
-	rows, err := fdr.docReaderDB.ReadDB().QueryxContext(ctx, fdr.queryString)
+	stmt, err := prepare(ctx, fdr.docReaderDB.ReadDB(), fdr.queryString)
+	if err != nil {
+		log.Err(err).Msgf("query: %s", fdr.queryString)
+		return er.WrapOp(err, op)
+	}
+	fdr.stmt = stmt
+
+	binds := partitionBinds(partCtx, partCtx.Min(), partCtx.Max())
+	switch partCtx.Type() {
+	case parallel.TimeRangeType:
+		from, to := parallel.TimeBounds(partCtx)
+		binds = []bind{{"from", from}, {"to", to}}
+	case parallel.HashType:
+		binds = hashBinds(partCtx)
+	}
+
+	rows, err := fdr.stmt.query(ctx, binds...)
 	if err != nil {
 		log.Err(err).Msgf("query: %s", fdr.queryString)
 		return er.WrapOp(err, op)
@@ -78,5 +106,28 @@ func (fdr *fullDocReader[T, R, J]) read(ctx context.Context) error {
 	fdr.rows = rows
 	fdr.readStatus = statusDoing
 
+	for ; fdr.skip > 0 && fdr.rows.Next(); fdr.skip-- {
+		fdr.consumed++
+	}
+	fdr.skip = 0
+
 	return nil
 }
+
+func (fdr *fullDocReader[T]) Close() error {
+	if fdr.rows != nil {
+		fdr.rows.Close()
+	}
+	if fdr.stmt != nil {
+		fdr.stmt.close()
+	}
+	return nil
+}
+
+func (fdr *fullDocReader[T]) Position() step.Checkpoint {
+	return step.Checkpoint{Offset: fdr.consumed + fdr.skip}
+}
+
+func (fdr *fullDocReader[T]) Seek(cp step.Checkpoint) {
+	fdr.skip = cp.Offset
+}
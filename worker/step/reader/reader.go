@@ -1,5 +1,16 @@
+// Package reader has the step.Reader of the sqlx queries (paging, full and keyset), the HTTP APIs
+// and the export files. The file readers read CSV (NewCsvFileReader) and NDJSON (NewNdjsonFileReader) only.
+// Parquet is out of scope because it needs an external dependency
 package reader
 
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/mapper"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
 type status int64
 
 const (
@@ -7,3 +18,139 @@ const (
 	statusDoing
 	statusFinish
 )
+
+// statement is the query prepared once by a reader and executed for every page
+// The query can use either named parameters (:from, :to for the range partitions, :limit, :offset for parallel.SortableIdType,
+// :from, :to, :limit, :offset for parallel.TimeRangeType and :bucket, :buckets, :limit, :offset for parallel.HashType)
+// or positional bind variables of the driver bound in the same order
+type statement struct {
+	stmt      *sqlx.NamedStmt
+	bindCount int
+}
+
+func prepare(ctx context.Context, db *sqlx.DB, query string) (*statement, error) {
+	stmt, err := db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statement{
+		stmt:      stmt,
+		bindCount: countBindVars(stmt.QueryString, sqlx.BindType(db.DriverName())),
+	}, nil
+}
+
+// bind is a parameter of the statement bound by name or by position
+type bind struct {
+	name  string
+	value any
+}
+
+// query executes the statement with binds that are the range [from, to] or the limit and offset by the type of partition
+func (s *statement) query(ctx context.Context, binds ...bind) (*sqlx.Rows, error) {
+	if len(s.stmt.Params) > 0 {
+		return s.stmt.QueryxContext(ctx, namedArgs(binds))
+	}
+
+	args := make([]any, 0, len(binds))
+	for _, b := range binds {
+		args = append(args, b.value)
+	}
+	if s.bindCount < len(args) {
+		args = args[:s.bindCount]
+	}
+
+	return s.stmt.Stmt.QueryxContext(ctx, args...)
+}
+
+func (s *statement) close() {
+	s.stmt.Close()
+}
+
+// scanner scans the rows into T by the columns of mapper.Fields, the same names the writers bind
+// The fields of the columns are resolved at the first row
+type scanner[T any] struct {
+	fields []mapper.Field
+	ptrs   []any
+}
+
+func (sc *scanner[T]) scan(rows *sqlx.Rows, item *T) error {
+	mapped := mapper.For[T]()
+
+	if sc.fields == nil {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+
+		fields, err := mapped.Select(columns)
+		if err != nil {
+			return err
+		}
+		sc.fields = fields
+	}
+
+	ptrs, err := mapped.Pointers(item, sc.fields, sc.ptrs[:0])
+	if err != nil {
+		return err
+	}
+	sc.ptrs = ptrs
+
+	return rows.Scan(ptrs...)
+}
+
+// partitionBinds binds a and b as :limit and :offset for parallel.SortableIdType or :from and :to for the others
+func partitionBinds(partCtx parallel.Partition, a, b any) []bind {
+	switch partCtx.Type() {
+	case parallel.SortableIdType:
+		return []bind{{"limit", a}, {"offset", b}}
+	default:
+		return []bind{{"from", a}, {"to", b}}
+	}
+}
+
+// timeBinds binds [:from, :to) of parallel.TimeRangeType partition followed by :limit and :offset of the page
+func timeBinds(partCtx parallel.Partition, limit, offset int64) []bind {
+	from, to := parallel.TimeBounds(partCtx)
+
+	return []bind{{"from", from}, {"to", to}, {"limit", limit}, {"offset", offset}}
+}
+
+// hashBinds binds :bucket and :buckets of parallel.HashType partition. see dialect.Dialect.HashBucket
+func hashBinds(partCtx parallel.Partition) []bind {
+	return []bind{{"bucket", partCtx.Min()}, {"buckets", partCtx.Max()}}
+}
+
+func namedArgs(binds []bind) map[string]any {
+	args := make(map[string]any, len(binds))
+	for _, b := range binds {
+		args[b.name] = b.value
+	}
+
+	return args
+}
+
+// countBindVars counts the positional bind variables out of the quoted strings
+func countBindVars(query string, bindType int) int {
+	var count int
+	var quote rune
+
+	for i, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?' && bindType != sqlx.DOLLAR:
+			count++
+		case r == '$' && bindType == sqlx.DOLLAR && i+1 < len(query) && strings.IndexByte("0123456789", query[i+1]) >= 0:
+			count++
+		case r == '@' && bindType == sqlx.AT && strings.HasPrefix(query[i+1:], "p"):
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,288 @@
+package reader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/rest"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Pagination string
+
+const (
+	PageNumber  Pagination = "page"   // ?page=1&size=100 until a page is not full
+	OffsetLimit Pagination = "offset" // ?offset=100&limit=100 until a page is not full
+	CursorToken Pagination = "cursor" // ?cursor=token&limit=100 with the token of the previous response until it is empty
+)
+
+// HttpOption is the option of NewHttpSource
+type HttpOption struct {
+	Client     *http.Client // http.DefaultClient if nil
+	Header     http.Header  // headers of every request (example. Authorization)
+	Pagination Pagination
+	PageSize   int64
+	FirstPage  int64  // number of the first page of PageNumber (example. 1)
+	PageParam  string // query parameter of the page, the offset or the cursor token. "page", "offset" or "cursor" if not set
+	SizeParam  string // query parameter of PageSize. "size" of PageNumber and "limit" of the others if not set
+	FromParam  string // query parameter of Min of the partition (RFC3339 from of parallel.TimeRangeType). the partition is not sent if not set
+	ToParam    string // query parameter of Max of the partition (RFC3339 to of parallel.TimeRangeType)
+	ItemsField string // dotted path of the items in the response (example. "data.items"). the response is the array of items if not set
+	TokenField string // dotted path of the next cursor token of CursorToken (example. "meta.next_cursor")
+	rest.Policy
+}
+
+// params returns the query parameters of the page and the size
+func (opt HttpOption) params() (string, string) {
+	page, size := opt.PageParam, opt.SizeParam
+	if page == "" {
+		page = string(opt.Pagination)
+	}
+	if size == "" {
+		size = "limit"
+		if opt.Pagination == PageNumber {
+			size = "size"
+		}
+	}
+	return page, size
+}
+
+type httpSource[T any] struct {
+	endpoint string
+	opt      HttpOption
+}
+
+// NewHttpSource returns step.Source of the JSON API of endpoint decoding the items into T by the json tags
+// The requests to a host are limited by rest.Policy and the transient failures (429, 5xx) are retried. see rest.Do
+func NewHttpSource[T any](endpoint string, opt HttpOption) step.Source[T] {
+	return &httpSource[T]{
+		endpoint: endpoint,
+		opt:      opt,
+	}
+}
+
+// NewHttpReader returns the reader of NewHttpSource
+func NewHttpReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](endpoint string, opt HttpOption) step.Reader[T, R, J] {
+	return NewCursorReader[T, R, J](NewHttpSource[T](endpoint, opt))
+}
+
+// Open returns the cursor of the pages of the partition. The cursor of PageNumber and OffsetLimit is step.Positioner
+func (hs *httpSource[T]) Open(_ context.Context, partCtx parallel.Partition) (step.Cursor[T], error) {
+	op := er.GetOperator()
+
+	switch hs.opt.Pagination {
+	case PageNumber, OffsetLimit, CursorToken:
+	default:
+		return nil, er.WrapOp(errors.Errorf("unsupported pagination [%s]", hs.opt.Pagination), op)
+	}
+
+	if hs.opt.PageSize <= 0 {
+		return nil, er.WrapOp(errors.New("need to set required settings [PageSize]"), op)
+	}
+
+	// the whole response would be sent as the next token
+	if hs.opt.Pagination == CursorToken && hs.opt.TokenField == "" {
+		return nil, er.WrapOp(errors.New("need to set required settings [TokenField]"), op)
+	}
+
+	u, err := url.Parse(hs.endpoint)
+	if err != nil {
+		return nil, er.WrapOp(err, op)
+	}
+
+	cursor := &httpCursor[T]{
+		endpoint:   u,
+		opt:        hs.opt,
+		partition:  partCtx,
+		readStatus: statusReady,
+	}
+
+	// the cursor token cannot be saved in step.Checkpoint, so the items are skipped again
+	if hs.opt.Pagination == CursorToken {
+		return cursor, nil
+	}
+
+	return &httpPageCursor[T]{cursor}, nil
+}
+
+type httpCursor[T any] struct {
+	endpoint   *url.URL
+	opt        HttpOption
+	partition  parallel.Partition
+	items      []json.RawMessage
+	next       int    // index of the next item in items
+	page       int64  // pages requested
+	token      string // cursor token of the next page
+	skip       int64  // items to skip in the next page after Seek
+	readStatus status
+}
+
+func (hc *httpCursor[T]) Next(ctx context.Context) (*T, bool, error) {
+	op := er.GetOperator()
+
+	for hc.next >= len(hc.items) {
+		if hc.readStatus == statusFinish {
+			return nil, true, nil
+		}
+		if err := hc.fetch(ctx); err != nil {
+			return nil, false, er.WrapOp(err, op)
+		}
+	}
+
+	// an item that failed to decode is consumed too
+	raw := hc.items[hc.next]
+	hc.next++
+
+	var item T
+	if err := json.Unmarshal(raw, &item); err != nil {
+		log.Err(err).Msgf("decode error")
+		return nil, false, er.WrapOpAndKind(err, op, er.KindInvalidItem)
+	}
+
+	return &item, false, nil
+}
+
+// fetch requests the next page of the partition
+func (hc *httpCursor[T]) fetch(ctx context.Context) error {
+	res, err := rest.Do(ctx, hc.opt.Client, hc.opt.Policy, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, hc.pageUrl(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range hc.opt.Header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		log.Err(err).Msgf("[%s] request: %s", hc.partition.PartitionName(), hc.endpoint.Redacted())
+		return err
+	}
+
+	items, token, err := hc.decode(res.Body)
+	if err != nil {
+		return err
+	}
+
+	hc.items, hc.next = items, 0
+	hc.page++
+	hc.readStatus = statusDoing
+
+	switch hc.opt.Pagination {
+	case CursorToken:
+		hc.token = token
+		if token == "" || len(items) == 0 {
+			hc.readStatus = statusFinish
+		}
+	default:
+		if int64(len(items)) < hc.opt.PageSize {
+			hc.readStatus = statusFinish
+		}
+	}
+
+	if hc.skip > 0 {
+		hc.next = int(minInt64(hc.skip, int64(len(items))))
+		hc.skip = 0
+	}
+
+	return nil
+}
+
+// pageUrl returns endpoint with the query parameters of the partition and the next page
+func (hc *httpCursor[T]) pageUrl() string {
+	pageParam, sizeParam := hc.opt.params()
+
+	q := hc.endpoint.Query()
+	q.Set(sizeParam, strconv.FormatInt(hc.opt.PageSize, 10))
+
+	switch hc.opt.Pagination {
+	case PageNumber:
+		q.Set(pageParam, strconv.FormatInt(hc.opt.FirstPage+hc.page, 10))
+	case OffsetLimit:
+		q.Set(pageParam, strconv.FormatInt(hc.page*hc.opt.PageSize, 10))
+	case CursorToken:
+		if hc.token != "" {
+			q.Set(pageParam, hc.token)
+		}
+	}
+
+	if hc.opt.FromParam != "" {
+		from, to := strconv.FormatInt(hc.partition.Min(), 10), strconv.FormatInt(hc.partition.Max(), 10)
+		if hc.partition.Type() == parallel.TimeRangeType {
+			fromTime, toTime := parallel.TimeBounds(hc.partition)
+			from, to = fromTime.Format(time.RFC3339Nano), toTime.Format(time.RFC3339Nano)
+		}
+		q.Set(hc.opt.FromParam, from)
+		if hc.opt.ToParam != "" {
+			q.Set(hc.opt.ToParam, to)
+		}
+	}
+
+	u := *hc.endpoint
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// decode returns the items and the next cursor token of the response
+func (hc *httpCursor[T]) decode(body []byte) ([]json.RawMessage, string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(rest.Field(body, hc.opt.ItemsField), &items); err != nil {
+		return nil, "", errors.Wrapf(err, "items [%s] of the response", hc.opt.ItemsField)
+	}
+
+	if hc.opt.Pagination != CursorToken {
+		return items, "", nil
+	}
+
+	raw := bytes.TrimSpace(rest.Field(body, hc.opt.TokenField))
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return items, "", nil
+	}
+
+	// the token can be a string or a number
+	var token string
+	if err := json.Unmarshal(raw, &token); err != nil {
+		token = string(raw)
+	}
+
+	return items, token, nil
+}
+
+func (hc *httpCursor[T]) Close() error {
+	hc.items = nil
+	return nil
+}
+
+// httpPageCursor is the cursor of PageNumber and OffsetLimit that can resume from the page of step.Checkpoint
+type httpPageCursor[T any] struct {
+	*httpCursor[T]
+}
+
+func (hpc *httpPageCursor[T]) Position() step.Checkpoint {
+	if hpc.readStatus == statusReady {
+		return step.Checkpoint{Page: hpc.page, Offset: hpc.skip}
+	}
+
+	return step.Checkpoint{Page: hpc.page - 1, Offset: int64(hpc.next)}
+}
+
+func (hpc *httpPageCursor[T]) Seek(cp step.Checkpoint) {
+	hpc.page = cp.Page
+	hpc.skip = cp.Offset
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,145 @@
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/rest"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFaqServer serves the ids [from, to] of the query in pages of the pagination
+func newFaqServer(t *testing.T, pagination Pagination, failFirst bool) (*httptest.Server, *[]string) {
+	var queries []string
+	var calls int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failFirst && atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		q := r.URL.Query()
+		queries = append(queries, r.URL.RawQuery)
+
+		from, _ := strconv.ParseInt(q.Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(q.Get("to"), 10, 64)
+		limit, _ := strconv.ParseInt(q.Get("limit"), 10, 64)
+
+		var offset int64
+		switch pagination {
+		case PageNumber:
+			page, _ := strconv.ParseInt(q.Get("page"), 10, 64)
+			limit, _ = strconv.ParseInt(q.Get("size"), 10, 64)
+			offset = (page - 1) * limit
+		case OffsetLimit:
+			offset, _ = strconv.ParseInt(q.Get("offset"), 10, 64)
+		case CursorToken:
+			offset, _ = strconv.ParseInt(q.Get("cursor"), 10, 64)
+		}
+
+		items := make([]json.RawMessage, 0)
+		for id := from + offset; id <= to && int64(len(items)) < limit; id++ {
+			item := fmt.Sprintf(`{"id":%d,"title":"t%d"}`, id, id)
+			if id == 4 {
+				item = `{"id":"four"}`
+			}
+			items = append(items, json.RawMessage(item))
+		}
+
+		var next any
+		if from+offset+int64(len(items)) <= to {
+			next = offset + int64(len(items))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"items": items}, "next": next})
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv, &queries
+}
+
+func Test_HttpReader(t *testing.T) {
+	pCtx := parallel.RestorePartition("pCtx0", 1, 5, 5, parallel.AutoIncrementIdType)
+
+	option := func(srv *httptest.Server, pagination Pagination) HttpOption {
+		return HttpOption{
+			Client:     srv.Client(),
+			Header:     http.Header{"Authorization": {"Bearer token"}},
+			Pagination: pagination,
+			PageSize:   2,
+			FirstPage:  1,
+			FromParam:  "from",
+			ToParam:    "to",
+			ItemsField: "data.items",
+			TokenField: "next",
+			Policy:     rest.Policy{MaxRetries: 1, RetryWait: time.Millisecond},
+		}
+	}
+
+	t.Run("read the pages of the partition", func(t *testing.T) {
+		for _, pagination := range []Pagination{PageNumber, OffsetLimit, CursorToken} {
+			srv, queries := newFaqServer(t, pagination, pagination == PageNumber)
+
+			r := NewHttpReader[faqRow, faqRow, step.EmptyDocProcessorParamType](srv.URL+"/faqs", option(srv, pagination)).New()
+			ids, failed := readAll(t, r, pCtx)
+			assert.Equal(t, []int64{1, 2, 3, 5}, ids, pagination)
+			assert.Equal(t, 1, failed, pagination)
+			assert.Len(t, *queries, 3, pagination)
+		}
+	})
+
+	t.Run("map the partition and the pages to the query", func(t *testing.T) {
+		srv, queries := newFaqServer(t, OffsetLimit, false)
+
+		readAll(t, NewHttpReader[faqRow, faqRow, step.EmptyDocProcessorParamType](srv.URL, option(srv, OffsetLimit)).New(), pCtx)
+		assert.Equal(t, []string{
+			"from=1&limit=2&offset=0&to=5",
+			"from=1&limit=2&offset=2&to=5",
+			"from=1&limit=2&offset=4&to=5",
+		}, *queries)
+	})
+
+	t.Run("resume from the page of the checkpoint", func(t *testing.T) {
+		srv, queries := newFaqServer(t, PageNumber, false)
+
+		r := NewHttpReader[faqRow, faqRow, step.EmptyDocProcessorParamType](srv.URL, option(srv, PageNumber)).New()
+		r.(step.Positioner).Seek(step.Checkpoint{Page: 1, Offset: 1})
+
+		ids, _ := readAll(t, r, pCtx)
+		assert.Equal(t, []int64{5}, ids)
+		assert.Len(t, *queries, 2)
+		assert.Equal(t, step.Checkpoint{Page: 2, Offset: 1}, r.(step.Positioner).Position())
+	})
+
+	t.Run("fail the cursor token without the token field", func(t *testing.T) {
+		srv, queries := newFaqServer(t, CursorToken, false)
+
+		opt := option(srv, CursorToken)
+		opt.TokenField = ""
+
+		r := NewHttpReader[faqRow, faqRow, step.EmptyDocProcessorParamType](srv.URL, opt).New()
+		_, _, err := r.Read(context.Background(), pCtx)
+		assert.Error(t, err)
+		assert.Empty(t, *queries)
+	})
+
+	t.Run("fail on the client error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer srv.Close()
+
+		r := NewHttpReader[faqRow, faqRow, step.EmptyDocProcessorParamType](srv.URL, option(srv, CursorToken)).New()
+		_, _, err := r.Read(context.Background(), pCtx)
+		assert.Error(t, err)
+	})
+}
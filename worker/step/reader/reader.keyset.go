@@ -0,0 +1,234 @@
+package reader
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math"
+)
+
+var errNoSortKey = errors.New("item must implement step.SortKeyer to be read by keyset")
+
+type keysetDocReader[T any] struct {
+	pageSize    int64
+	queryString string // need to set format "SELECT * FROM faqs WHERE faqs.id > :last AND faqs.id <= :max ORDER BY faqs.id LIMIT :limit OFFSET :offset"
+	docReaderDB step.ReaderDB
+	partition   parallel.Partition
+	stmt        *sqlx.NamedStmt
+	rows        *sqlx.Rows
+	scanner     scanner[T]
+	last        int64 // sort key of the last item read
+	failed      int64 // rows after last that failed to scan. the next page skips them by :offset
+	consumed    int64 // items read in the partition
+	pageRead    int64 // items read in the current page
+	pageLimit   int64
+	readStatus  status
+}
+
+// NewKeysetDocReader returns Reader that seeks by the sort key of the last item instead of growing OFFSET
+// T must implement step.SortKeyer
+// For parallel.KeysetType and parallel.AutoIncrementIdType partitions :last starts from Min()-1 and :max is Max()
+// For parallel.SortableIdType partitions :offset is the offset of the partition for the first page only and :max is not bounded
+func NewKeysetDocReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](pageSize int64, queryString string, db step.ReaderDB) step.Reader[T, R, J] {
+	return NewCursorReader[T, R, J](&keysetDocReader[T]{
+		pageSize:    pageSize,
+		queryString: queryString,
+		docReaderDB: db,
+	})
+}
+
+// Open returns the cursor of the pages of the partition. see step.Source
+func (kdr *keysetDocReader[T]) Open(_ context.Context, partCtx parallel.Partition) (step.Cursor[T], error) {
+	return &keysetDocReader[T]{
+		pageSize:    kdr.pageSize,
+		queryString: kdr.queryString,
+		docReaderDB: kdr.docReaderDB,
+		partition:   partCtx,
+		readStatus:  statusReady,
+	}, nil
+}
+
+func (kdr *keysetDocReader[T]) Next(ctx context.Context) (*T, bool, error) {
+	op := er.GetOperator()
+	partCtx := kdr.partition
+
+	if kdr.readStatus == statusFinish {
+		return nil, true, nil
+	}
+
+	if kdr.readStatus == statusReady {
+		if err := kdr.prepare(ctx, partCtx); err != nil {
+			return nil, false, er.WrapOp(err, op)
+		}
+	}
+
+	if kdr.rows == nil {
+		if kdr.remaining(partCtx) <= 0 {
+			kdr.finish()
+			return nil, true, nil
+		}
+
+		if err := kdr.read(ctx, partCtx); err != nil {
+			return nil, false, er.WrapOp(err, op)
+		}
+	}
+
+	if !kdr.rows.Next() {
+		if err := kdr.rows.Err(); err != nil {
+			return nil, false, er.WrapOp(err, op)
+		}
+
+		kdr.rows.Close()
+		kdr.rows = nil
+
+		// a short page is the last page
+		if kdr.pageRead < kdr.pageLimit {
+			kdr.finish()
+			return nil, true, nil
+		}
+
+		return nil, false, nil
+	}
+
+	// a row that failed to scan is consumed too
+	kdr.consumed++
+	kdr.pageRead++
+
+	var item T
+	if err := kdr.scanner.scan(kdr.rows, &item); err != nil {
+		log.Err(err).Msgf("scan error")
+		kdr.failed++
+		return nil, false, er.WrapOpAndKind(err, op, er.KindInvalidItem)
+	}
+
+	key, err := sortKey(&item)
+	if err != nil {
+		return nil, false, er.WrapOp(err, op)
+	}
+
+	kdr.last = key
+	kdr.failed = 0
+
+	return &item, false, nil
+}
+
+func (kdr *keysetDocReader[T]) prepare(ctx context.Context, partCtx parallel.Partition) error {
+	op := er.GetOperator()
+
+	stmt, err := kdr.docReaderDB.ReadDB().PrepareNamedContext(ctx, kdr.queryString)
+	if err != nil {
+		log.Err(err).Msgf("query: %s", kdr.queryString)
+		return er.WrapOp(err, op)
+	}
+
+	kdr.stmt = stmt
+	kdr.readStatus = statusDoing
+
+	// not resumed by Seek
+	if kdr.consumed == 0 {
+		switch partCtx.Type() {
+		case parallel.SortableIdType, parallel.HashType:
+			kdr.last = math.MinInt64
+		default:
+			kdr.last = partCtx.Min() - 1
+		}
+	}
+
+	return nil
+}
+
+func (kdr *keysetDocReader[T]) read(ctx context.Context, partCtx parallel.Partition) error {
+	op := er.GetOperator()
+
+	max, offset := int64(math.MaxInt64), int64(0)
+
+	switch partCtx.Type() {
+	case parallel.SortableIdType:
+		if kdr.consumed == 0 {
+			offset = partCtx.Max()
+		}
+	case parallel.HashType:
+		// the bucket is filtered by :bucket and :buckets over the whole key range
+	default:
+		max = partCtx.Max()
+	}
+
+	// the key of a row that failed to scan is unknown, so the rows ending the previous page are skipped by the offset
+	offset += kdr.failed
+
+	kdr.pageLimit = kdr.pageSize
+	if remaining := kdr.remaining(partCtx); remaining < kdr.pageLimit {
+		kdr.pageLimit = remaining
+	}
+
+	args := map[string]any{
+		"last":   kdr.last,
+		"max":    max,
+		"limit":  kdr.pageLimit,
+		"offset": offset,
+	}
+
+	if partCtx.Type() == parallel.HashType {
+		args["bucket"], args["buckets"] = partCtx.Min(), partCtx.Max()
+	}
+
+	rows, err := kdr.stmt.QueryxContext(ctx, args)
+	if err != nil {
+		log.Err(err).Msgf("query: %s, args: %v", kdr.queryString, args)
+		return er.WrapOp(err, op)
+	}
+
+	kdr.rows = rows
+	kdr.pageRead = 0
+
+	return nil
+}
+
+// remaining returns the number of items left in SortableIdType partition whose Min() is the limit
+func (kdr *keysetDocReader[T]) remaining(partCtx parallel.Partition) int64 {
+	if partCtx.Type() != parallel.SortableIdType {
+		return math.MaxInt64
+	}
+	return partCtx.Min() - kdr.consumed
+}
+
+func (kdr *keysetDocReader[T]) finish() {
+	if kdr.rows != nil {
+		kdr.rows.Close()
+		kdr.rows = nil
+	}
+	if kdr.stmt != nil {
+		kdr.stmt.Close()
+	}
+	kdr.readStatus = statusFinish
+}
+
+func (kdr *keysetDocReader[T]) Close() error {
+	if kdr.readStatus != statusReady {
+		kdr.finish()
+	}
+	return nil
+}
+
+func (kdr *keysetDocReader[T]) Position() step.Checkpoint {
+	return step.Checkpoint{Offset: kdr.consumed, Key: kdr.last}
+}
+
+func (kdr *keysetDocReader[T]) Seek(cp step.Checkpoint) {
+	kdr.consumed = cp.Offset
+	kdr.last = cp.Key
+}
+
+func sortKey[T any](item *T) (int64, error) {
+	if sk, ok := any(item).(step.SortKeyer); ok {
+		return sk.SortKey(), nil
+	}
+	if sk, ok := any(*item).(step.SortKeyer); ok {
+		return sk.SortKey(), nil
+	}
+	return 0, errNoSortKey
+}
@@ -0,0 +1,220 @@
+package reader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math"
+	"testing"
+)
+
+type keysetRow struct {
+	Id int64 `db:"id"`
+}
+
+func (r keysetRow) ToModel(*step.EmptyDocProcessorParamType) (*keysetRow, error) {
+	return &r, nil
+}
+
+func (r keysetRow) SortKey() int64 {
+	return r.Id
+}
+
+type readerDBMock struct {
+	db *sqlx.DB
+}
+
+func (m *readerDBMock) GetSortBy(string) (parallel.Partition, error) { return nil, nil }
+func (m *readerDBMock) GetReadQuery(string) string                   { return "" }
+func (m *readerDBMock) ReadDB() *sqlx.DB                             { return m.db }
+
+// idsConnMock is the database/sql driver of a table of the sorted ids
+// It answers "id > ? AND (id <= ? or MOD(id, ?) = ?) LIMIT ? OFFSET ?" and records the arguments of every query
+// The ids of invalid are returned as text that cannot be scanned
+type idsConnMock struct {
+	ids     []int64
+	invalid map[int64]bool
+	args    [][]driver.Value
+}
+
+func newIdsDBMock(ids ...int64) (*sqlx.DB, *idsConnMock) {
+	conn := &idsConnMock{ids: ids}
+	return sqlx.NewDb(sql.OpenDB(conn), "mysql"), conn
+}
+
+func (c *idsConnMock) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *idsConnMock) Driver() driver.Driver                        { return nil }
+func (c *idsConnMock) Prepare(string) (driver.Stmt, error)          { return c, nil }
+func (c *idsConnMock) Close() error                                 { return nil }
+func (c *idsConnMock) Begin() (driver.Tx, error)                    { return nil, io.EOF }
+func (c *idsConnMock) NumInput() int                                { return -1 }
+
+func (c *idsConnMock) Exec([]driver.Value) (driver.Result, error) {
+	return nil, io.EOF
+}
+
+func (c *idsConnMock) Query(args []driver.Value) (driver.Rows, error) {
+	c.args = append(c.args, args)
+
+	last, max := args[0].(int64), int64(math.MaxInt64)
+	buckets, bucket := int64(1), int64(0)
+	switch len(args) {
+	case 4:
+		max = args[1].(int64)
+	case 5:
+		buckets, bucket = args[1].(int64), args[2].(int64)
+	}
+	limit, offset := args[len(args)-2].(int64), args[len(args)-1].(int64)
+
+	rows := &idsRowsMock{invalid: c.invalid}
+	for _, id := range c.ids {
+		if id <= last || id > max || id%buckets != bucket {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if int64(len(rows.ids)) == limit {
+			break
+		}
+		rows.ids = append(rows.ids, id)
+	}
+
+	return rows, nil
+}
+
+type idsRowsMock struct {
+	ids     []int64
+	invalid map[int64]bool
+}
+
+func (r *idsRowsMock) Columns() []string { return []string{"id"} }
+func (r *idsRowsMock) Close() error      { return nil }
+
+func (r *idsRowsMock) Next(dest []driver.Value) error {
+	if len(r.ids) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.ids[0]
+	if r.invalid[r.ids[0]] {
+		dest[0] = "invalid"
+	}
+	r.ids = r.ids[1:]
+	return nil
+}
+
+func Test_KeysetDocReader(t *testing.T) {
+	const (
+		rangeQuery = "SELECT id FROM faqs WHERE id > :last AND id <= :max ORDER BY id LIMIT :limit OFFSET :offset"
+		hashQuery  = "SELECT id FROM faqs WHERE id > :last AND MOD(id, :buckets) = :bucket ORDER BY id LIMIT :limit OFFSET :offset"
+	)
+	ids := []int64{1, 2, 3, 5, 6, 7, 8, 9, 10}
+
+	readAll := func(r step.Reader[keysetRow, keysetRow, step.EmptyDocProcessorParamType], p parallel.Partition) []int64 {
+		var read []int64
+		for {
+			item, done, err := r.Read(context.Background(), p)
+			assert.NoError(t, err)
+			if done {
+				return read
+			}
+			if item != nil {
+				read = append(read, item.Id)
+			}
+		}
+	}
+
+	lasts := func(conn *idsConnMock) []driver.Value {
+		var values []driver.Value
+		for _, args := range conn.args {
+			values = append(values, args[0])
+		}
+		return values
+	}
+
+	t.Run("advance :last by the sort key of the last item", func(t *testing.T) {
+		db, conn := newIdsDBMock(ids...)
+		r := NewKeysetDocReader[keysetRow, keysetRow, step.EmptyDocProcessorParamType](3, rangeQuery, &readerDBMock{db})
+
+		read := readAll(r, parallel.RestorePartition("pCtx0", 2, 9, 0, parallel.KeysetType))
+
+		assert.Equal(t, []int64{2, 3, 5, 6, 7, 8, 9}, read)
+		assert.Equal(t, []driver.Value{int64(1), int64(5), int64(8)}, lasts(conn))
+		assert.Equal(t, []driver.Value{int64(8), int64(9), int64(3), int64(0)}, conn.args[2])
+		assert.Equal(t, step.Checkpoint{Offset: 7, Key: 9}, r.(step.Positioner).Position())
+	})
+
+	t.Run("skip the row that failed to scan at the end of the page", func(t *testing.T) {
+		db, conn := newIdsDBMock(ids...)
+		conn.invalid = map[int64]bool{5: true}
+		r := NewKeysetDocReader[keysetRow, keysetRow, step.EmptyDocProcessorParamType](3, rangeQuery, &readerDBMock{db})
+		p := parallel.RestorePartition("pCtx0", 2, 9, 0, parallel.KeysetType)
+
+		var read []int64
+		var failed int
+		for {
+			item, done, err := r.Read(context.Background(), p)
+			if err != nil {
+				assert.True(t, er.IsKind(err, er.KindInvalidItem))
+				failed++
+				continue
+			}
+			if done {
+				break
+			}
+			if item != nil {
+				read = append(read, item.Id)
+			}
+		}
+
+		assert.Equal(t, []int64{2, 3, 6, 7, 8, 9}, read)
+		assert.Equal(t, 1, failed)
+		// the next page starts after the failed row by the offset
+		assert.Equal(t, []driver.Value{int64(3), int64(9), int64(3), int64(1)}, conn.args[1])
+		assert.Equal(t, []driver.Value{int64(1), int64(3), int64(8)}, lasts(conn))
+	})
+
+	t.Run("resume from the key of the checkpoint", func(t *testing.T) {
+		db, conn := newIdsDBMock(ids...)
+		r := NewKeysetDocReader[keysetRow, keysetRow, step.EmptyDocProcessorParamType](3, rangeQuery, &readerDBMock{db})
+		r.(step.Positioner).Seek(step.Checkpoint{Offset: 3, Key: 5})
+
+		read := readAll(r, parallel.RestorePartition("pCtx0", 2, 9, 0, parallel.KeysetType))
+
+		assert.Equal(t, []int64{6, 7, 8, 9}, read)
+		assert.Equal(t, []driver.Value{int64(5), int64(8)}, lasts(conn))
+		assert.Equal(t, step.Checkpoint{Offset: 7, Key: 9}, r.(step.Positioner).Position())
+	})
+
+	t.Run("read the limit from the offset of sortable id partition", func(t *testing.T) {
+		db, conn := newIdsDBMock(ids...)
+		r := NewKeysetDocReader[keysetRow, keysetRow, step.EmptyDocProcessorParamType](3, rangeQuery, &readerDBMock{db})
+
+		// the limit 4 from the offset 2
+		read := readAll(r, parallel.RestorePartition("pCtx1", 4, 2, 0, parallel.SortableIdType))
+
+		assert.Equal(t, []int64{3, 5, 6, 7}, read)
+		// the offset is only for the first page, and the last page is the rest of the limit
+		assert.Equal(t, []driver.Value{int64(math.MinInt64), int64(math.MaxInt64), int64(3), int64(2)}, conn.args[0])
+		assert.Equal(t, []driver.Value{int64(6), int64(math.MaxInt64), int64(1), int64(0)}, conn.args[1])
+		assert.Len(t, conn.args, 2)
+	})
+
+	t.Run("read the bucket of hash partition over the whole keys", func(t *testing.T) {
+		db, conn := newIdsDBMock(ids...)
+		r := NewKeysetDocReader[keysetRow, keysetRow, step.EmptyDocProcessorParamType](3, hashQuery, &readerDBMock{db})
+
+		read := readAll(r, parallel.RestorePartition("pCtx1", 1, 2, 0, parallel.HashType))
+
+		assert.Equal(t, []int64{1, 3, 5, 7, 9}, read)
+		assert.Equal(t, []driver.Value{int64(math.MinInt64), int64(2), int64(1), int64(3), int64(0)}, conn.args[0])
+		assert.Equal(t, []driver.Value{int64(math.MinInt64), int64(5)}, lasts(conn))
+	})
+}
@@ -0,0 +1,132 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+)
+
+type ndjsonFileReader[T any] struct {
+	source     string // file path or glob pattern of parallel.Files
+	partition  parallel.Partition
+	file       *os.File
+	lines      *bufio.Reader
+	consumed   int64 // records read
+	skip       int64 // records to skip after Seek
+	readStatus status
+}
+
+// NewNdjsonFileReader returns the reader decoding every line of source into T by the json tags. The blank lines are ignored
+// Partition the file by parallel.FileRanges or the glob by parallel.Files
+func NewNdjsonFileReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](source string) step.Reader[T, R, J] {
+	return NewCursorReader[T, R, J](&ndjsonFileReader[T]{
+		source: source,
+	})
+}
+
+// Open returns the cursor of the records of the partition. see step.Source
+func (nfr *ndjsonFileReader[T]) Open(_ context.Context, partCtx parallel.Partition) (step.Cursor[T], error) {
+	return &ndjsonFileReader[T]{
+		source:     nfr.source,
+		partition:  partCtx,
+		readStatus: statusReady,
+	}, nil
+}
+
+func (nfr *ndjsonFileReader[T]) Next(_ context.Context) (*T, bool, error) {
+	op := er.GetOperator()
+
+	switch nfr.readStatus {
+	case statusReady:
+		if err := nfr.open(nfr.partition); err != nil {
+			log.Err(err).Msgf("file: %s", nfr.source)
+			return nil, false, er.WrapOp(err, op)
+		}
+	case statusFinish:
+		return nil, true, nil
+	}
+
+	line, err := nfr.next()
+	if err == io.EOF {
+		nfr.Close()
+		nfr.readStatus = statusFinish
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, er.WrapOp(err, op)
+	}
+
+	// a line that failed to decode is consumed too
+	nfr.consumed++
+
+	var item T
+	if err := json.Unmarshal(line, &item); err != nil {
+		log.Err(err).Msgf("decode error")
+		return nil, false, er.WrapOpAndKind(err, op, er.KindInvalidItem)
+	}
+
+	return &item, false, nil
+}
+
+func (nfr *ndjsonFileReader[T]) open(partCtx parallel.Partition) error {
+	f, r, err := openFile(nfr.source, partCtx)
+	if err != nil {
+		return err
+	}
+	nfr.file = f
+	nfr.lines = bufio.NewReader(r)
+
+	for ; nfr.skip > 0; nfr.skip-- {
+		if _, err := nfr.next(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		nfr.consumed++
+	}
+	nfr.skip = 0
+
+	nfr.readStatus = statusDoing
+	return nil
+}
+
+// next returns the next line that is not blank
+func (nfr *ndjsonFileReader[T]) next() ([]byte, error) {
+	for {
+		line, err := nfr.lines.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			// the last line can end without a line break
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (nfr *ndjsonFileReader[T]) Close() error {
+	if nfr.file == nil {
+		return nil
+	}
+
+	f := nfr.file
+	nfr.file = nil
+
+	return f.Close()
+}
+
+func (nfr *ndjsonFileReader[T]) Position() step.Checkpoint {
+	return step.Checkpoint{Offset: nfr.consumed + nfr.skip}
+}
+
+func (nfr *ndjsonFileReader[T]) Seek(cp step.Checkpoint) {
+	nfr.skip = cp.Offset
+}
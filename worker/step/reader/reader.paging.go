@@ -2,7 +2,6 @@ package reader
 
 import (
 	"context"
-	"fmt"
 	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
@@ -10,48 +9,71 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-type pagingDocReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]] struct {
+type pagingDocReader[T any] struct {
 	pageSize    int64
-	queryString string // need to set format "SELECT * FROM faqs WHERE faqs.id >= %d and fqs.id < %d"
+	queryString string // need to set format "SELECT * FROM faqs WHERE faqs.id >= :from AND faqs.id <= :to" or "... LIMIT :limit OFFSET :offset"
+	// parallel.TimeRangeType binds both as "... WHERE created_at >= :from AND created_at < :to ORDER BY created_at, id LIMIT :limit OFFSET :offset"
+	// and parallel.HashType as "... WHERE MOD(CRC32(`id`), :buckets) = :bucket ORDER BY id LIMIT :limit OFFSET :offset"
 	docReaderDB step.ReaderDB
+	partition   parallel.Partition
+	stmt        *statement
 	rows        *sqlx.Rows
+	scanner     scanner[T]
 	page        int64
+	consumed    int64 // items read in the current page
+	skip        int64 // items to skip in the next page after Seek
 	hasNext     bool
+	untilShort  bool // parallel.TimeRangeType and parallel.HashType are read until a page is not full
 	readStatus  status
 }
 
 func NewPagingDocReader[T step.DocProcessor[R, J], R any, J step.ProcessorParam[J]](pageSize int64, queryString string, db step.ReaderDB) step.Reader[T, R, J] {
-	return &pagingDocReader[T, R, J]{
+	return NewCursorReader[T, R, J](&pagingDocReader[T]{
 		pageSize:    pageSize,
 		queryString: queryString,
 		docReaderDB: db,
-		page:        0,
-		hasNext:     false,
-		readStatus:  statusReady,
-	}
+	})
 }
 
-func (pdr *pagingDocReader[T, R, J]) New() step.Reader[T, R, J] {
-	return &pagingDocReader[T, R, J]{
+// Open returns the cursor of the pages of the partition. see step.Source
+func (pdr *pagingDocReader[T]) Open(_ context.Context, partCtx parallel.Partition) (step.Cursor[T], error) {
+	return &pagingDocReader[T]{
 		pageSize:    pdr.pageSize,
 		queryString: pdr.queryString,
 		docReaderDB: pdr.docReaderDB,
-		page:        pdr.page,
-		hasNext:     pdr.hasNext,
-		readStatus:  pdr.readStatus,
-	}
+		partition:   partCtx,
+		readStatus:  statusReady,
+	}, nil
 }
 
-func (pdr *pagingDocReader[T, R, J]) Read(ctx context.Context, partCtx parallel.Partition) (*T, bool, error) {
+func (pdr *pagingDocReader[T]) Next(ctx context.Context) (*T, bool, error) {
 	op := er.GetOperator()
+	partCtx := pdr.partition
 
 	if !pdr.hasNext && pdr.readStatus == statusReady {
 
-		from, to := pdr.getPagination(partCtx)
-
-		from, to = pdr.checkLimitPage(from, to, partCtx)
+		var binds []bind
+		switch partCtx.Type() {
+		case parallel.TimeRangeType:
+			binds = timeBinds(partCtx, pdr.pageSize, pdr.page*pdr.pageSize)
+			pdr.untilShort = true
+		case parallel.HashType:
+			binds = append(hashBinds(partCtx), bind{"limit", pdr.pageSize}, bind{"offset", pdr.page * pdr.pageSize})
+			pdr.untilShort = true
+		default:
+			from, to := pdr.getPagination(partCtx)
+
+			// the claimed page is not given away by parallel.SplitPartition.Split
+			if sp, ok := partCtx.(parallel.SplitPartition); ok && partCtx.Type() == parallel.AutoIncrementIdType {
+				to = sp.Claim(to)
+			}
+
+			from, to = pdr.checkLimitPage(from, to, partCtx)
+
+			binds = partitionBinds(partCtx, from, to)
+		}
 
-		if err := pdr.read(ctx, from, to); err != nil {
+		if err := pdr.read(ctx, binds); err != nil {
 			return nil, false, er.WrapOp(err, op)
 		}
 
@@ -60,9 +82,6 @@ func (pdr *pagingDocReader[T, R, J]) Read(ctx context.Context, partCtx parallel.
 	}
 
 	if !pdr.hasNext && pdr.readStatus == statusFinish {
-
-		pdr.rows.Close()
-
 		return nil, true, nil
 	}
 
@@ -71,29 +90,38 @@ func (pdr *pagingDocReader[T, R, J]) Read(ctx context.Context, partCtx parallel.
 	return item, false, err
 }
 
-func (pdr *pagingDocReader[T, R, J]) getItem() (*T, error) {
+func (pdr *pagingDocReader[T]) getItem() (*T, error) {
 	op := er.GetOperator()
 
 	hasNext := pdr.rows.Next()
 	pdr.hasNext = hasNext
 
 	if !hasNext {
+		if err := pdr.rows.Err(); err != nil {
+			return nil, er.WrapOp(err, op)
+		}
+		if pdr.untilShort && pdr.consumed < pdr.pageSize {
+			pdr.readStatus = statusFinish
+		}
 		return nil, nil
 	}
 
+	// a row that failed to scan is consumed too
+	pdr.consumed++
+
 	var item T
-	if err := pdr.rows.StructScan(&item); err != nil {
+	if err := pdr.scanner.scan(pdr.rows, &item); err != nil {
 		log.Err(err).Msgf("scan error")
-		return nil, er.WrapOp(err, op)
+		return nil, er.WrapOpAndKind(err, op, er.KindInvalidItem)
 	}
 
 	return &item, nil
 
 }
 
-func (pdr *pagingDocReader[T, R, J]) getPagination(partCtx parallel.Partition) (int64, int64) {
+func (pdr *pagingDocReader[T]) getPagination(partCtx parallel.Partition) (int64, int64) {
 	switch partCtx.Type() {
-	case parallel.AutoIncrementIdType:
+	case parallel.AutoIncrementIdType, parallel.KeysetType:
 		from := partCtx.Min() + (pdr.page * pdr.pageSize)
 		to := from + pdr.pageSize - 1
 
@@ -108,32 +136,68 @@ func (pdr *pagingDocReader[T, R, J]) getPagination(partCtx parallel.Partition) (
 	return 0, 0
 }
 
-func (pdr *pagingDocReader[T, R, J]) read(ctx context.Context, from, to any) error {
+func (pdr *pagingDocReader[T]) read(ctx context.Context, binds []bind) error {
 	op := er.GetOperator()
 
 	if pdr.rows != nil {
 		pdr.rows.Close()
 	}
 
-	q := fmt.Sprintf(pdr.queryString, from, to)
+	if pdr.stmt == nil {
+		stmt, err := prepare(ctx, pdr.docReaderDB.ReadDB(), pdr.queryString)
+		if err != nil {
+			log.Err(err).Msgf("query: %s", pdr.queryString)
+			return er.WrapOp(err, op)
+		}
+		pdr.stmt = stmt
+	}
 
-	rows, err := pdr.docReaderDB.ReadDB().QueryxContext(ctx, q)
+	rows, err := pdr.stmt.query(ctx, binds...)
 	if err != nil {
-		log.Err(err).Msgf("query: %s", q)
+		log.Err(err).Msgf("query: %s, args: %v", pdr.queryString, binds)
 		return er.WrapOp(err, op)
 	}
 
 	pdr.rows = rows
 
 	pdr.page++
+	pdr.consumed = 0
+
+	for ; pdr.skip > 0 && pdr.rows.Next(); pdr.skip-- {
+		pdr.consumed++
+	}
+	pdr.skip = 0
 
 	return nil
 }
 
-func (pdr *pagingDocReader[T, R, J]) checkLimitPage(from, to int64, parCtx parallel.Partition) (int64, int64) {
+func (pdr *pagingDocReader[T]) Close() error {
+	if pdr.rows != nil {
+		pdr.rows.Close()
+	}
+	if pdr.stmt != nil {
+		pdr.stmt.close()
+	}
+	return nil
+}
+
+func (pdr *pagingDocReader[T]) Position() step.Checkpoint {
+	if pdr.rows == nil {
+		return step.Checkpoint{Page: pdr.page, Offset: pdr.skip}
+	}
+
+	return step.Checkpoint{Page: pdr.page - 1, Offset: pdr.consumed}
+}
+
+func (pdr *pagingDocReader[T]) Seek(cp step.Checkpoint) {
+	pdr.page = cp.Page
+	pdr.skip = cp.Offset
+}
+
+func (pdr *pagingDocReader[T]) checkLimitPage(from, to int64, parCtx parallel.Partition) (int64, int64) {
 
 	switch parCtx.Type() {
-	case parallel.AutoIncrementIdType:
+	case parallel.AutoIncrementIdType, parallel.KeysetType:
 		if to >= parCtx.Max() {
 			pdr.readStatus = statusFinish
 			return from, parCtx.Max()
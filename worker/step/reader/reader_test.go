@@ -0,0 +1,54 @@
+package reader
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_CountBindVars(t *testing.T) {
+	t.Run("count positional bind variables of each bind type", func(t *testing.T) {
+		testData := []struct {
+			query    string
+			bindType int
+			count    int
+		}{
+			{"SELECT * FROM faqs WHERE id >= ? AND id <= ?", sqlx.QUESTION, 2},
+			{"SELECT * FROM faqs WHERE id >= $1 AND id <= $2", sqlx.DOLLAR, 2},
+			{"SELECT * FROM faqs WHERE title = '?' AND id >= ?", sqlx.QUESTION, 1},
+			{"SELECT * FROM faqs WHERE price > '$1'", sqlx.DOLLAR, 0},
+			{"SELECT * FROM faqs", sqlx.QUESTION, 0},
+		}
+
+		for _, td := range testData {
+			assert.Equal(t, td.count, countBindVars(td.query, td.bindType), td.query)
+		}
+	})
+}
+
+func Test_NamedArgs(t *testing.T) {
+	t.Run("bind range or limit and offset by partition type", func(t *testing.T) {
+		assert.Equal(t, map[string]any{"from": int64(1), "to": int64(100)},
+			namedArgs(partitionBinds(parallel.RestorePartition("", 1, 100, 0, parallel.AutoIncrementIdType), int64(1), int64(100))))
+
+		assert.Equal(t, map[string]any{"limit": int64(301), "offset": int64(602)},
+			namedArgs(partitionBinds(parallel.RestorePartition("", 301, 602, 0, parallel.SortableIdType), int64(301), int64(602))))
+	})
+
+	t.Run("bind bucket of hash partition", func(t *testing.T) {
+		assert.Equal(t, map[string]any{"bucket": int64(2), "buckets": int64(8)},
+			namedArgs(hashBinds(parallel.RestorePartition("", 2, 8, 0, parallel.HashType))))
+	})
+
+	t.Run("bind time bounds with the page of time range partition", func(t *testing.T) {
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(0, 0, 1)
+
+		binds := timeBinds(parallel.RestorePartition("", from.UnixNano(), to.UnixNano(), 0, parallel.TimeRangeType), 100, 200)
+
+		assert.Equal(t, map[string]any{"from": from, "to": to, "limit": int64(100), "offset": int64(200)}, namedArgs(binds))
+		assert.Equal(t, []string{"from", "to", "limit", "offset"}, []string{binds[0].name, binds[1].name, binds[2].name, binds[3].name})
+	})
+}
@@ -0,0 +1,181 @@
+package step
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/rs/zerolog/log"
+)
+
+// Committer is Writer that writes the chunks in a transaction
+// The step commits the transaction every Option.CommitInterval chunks and saves the checkpoint only after the commit
+type Committer interface {
+	// Commit commits the chunks written since the last commit
+	Commit() error
+	// Rollback discards the chunks written since the last commit. It does nothing if no chunk is written
+	Rollback() error
+}
+
+// Completer is Writer that completes the output of a partition after every chunk of it is committed (example. renames the partial file)
+// It is not called when the partition fails or is cancelled
+type Completer interface {
+	Complete(parallel.Partition) error
+}
+
+// Resumer is Writer that needs to know the partition resumes from the checkpoint (example. the file writer cannot append to the partial file)
+// The step fails the partition with the error of Resume before reading any item
+type Resumer interface {
+	Resume(parallel.Partition, Checkpoint) error
+}
+
+// chunkWriter writes the chunks of a partition with retries
+// With Committer, the chunks not committed yet are kept to write them again after a rollback
+type chunkWriter[R any] struct {
+	write     func([]R) (int64, error)
+	retry     func(func() error) error
+	committer Committer
+	interval  int
+	pending   [][]R
+	rejected  *RejectedError // items of the last write rejected by the writer
+}
+
+func newChunkWriter[R any](write func([]R) (int64, error), retry func(func() error) error, writer any, interval int) *chunkWriter[R] {
+	committer, _ := writer.(Committer)
+	if interval < 1 {
+		interval = 1
+	}
+
+	return &chunkWriter[R]{
+		write:     write,
+		retry:     retry,
+		committer: committer,
+		interval:  interval,
+	}
+}
+
+// writeChunk writes items and returns the affected count and whether the written chunks are committed
+func (cw *chunkWriter[R]) writeChunk(items []R) (int64, bool, error) {
+	return cw.writeCommit(items, len(cw.pending)+1 >= cw.interval)
+}
+
+func (cw *chunkWriter[R]) writeCommit(items []R, commit bool) (int64, bool, error) {
+	var rowsAff int64
+
+	cw.rejected = nil
+
+	if cw.committer == nil {
+		err := cw.retry(func() (err error) {
+			rowsAff, err = cw.writeItems(items)
+			return
+		})
+		return rowsAff, true, err
+	}
+
+	rolledBack := false
+	err := cw.retry(func() (err error) {
+		if rolledBack {
+			if err := cw.replay(); err != nil {
+				return cw.rollback(err)
+			}
+		}
+
+		rowsAff, err = cw.writeItems(items)
+		if err != nil {
+			rolledBack = true
+			return cw.rollback(err)
+		}
+
+		if commit {
+			if err := cw.committer.Commit(); err != nil {
+				rolledBack = true
+				return cw.rollback(err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		// the pending chunks are rolled back too. commit(true) writes them again
+		return 0, false, err
+	}
+
+	if commit {
+		cw.pending = nil
+	} else {
+		cw.pending = append(cw.pending, items)
+	}
+
+	return rowsAff, commit, nil
+}
+
+// commit commits the pending chunks. It writes them again if the transaction was rolled back
+func (cw *chunkWriter[R]) commit(rolledBack bool) error {
+	if cw.committer == nil || (len(cw.pending) == 0 && !rolledBack) {
+		return nil
+	}
+
+	err := cw.retry(func() error {
+		if rolledBack {
+			if err := cw.replay(); err != nil {
+				return cw.rollback(err)
+			}
+		}
+
+		if err := cw.committer.Commit(); err != nil {
+			rolledBack = true
+			return cw.rollback(err)
+		}
+
+		return nil
+	})
+
+	cw.pending = nil
+
+	return err
+}
+
+// discard rolls back the pending chunks
+func (cw *chunkWriter[R]) discard() {
+	if cw.committer == nil {
+		return
+	}
+
+	if err := cw.committer.Rollback(); err != nil {
+		log.Err(err).Msg("failed to rollback")
+	}
+	cw.pending = nil
+}
+
+// writeItems writes items and keeps RejectedError of the writer to skip the rejected items. see takeRejected
+func (cw *chunkWriter[R]) writeItems(items []R) (int64, error) {
+	rowsAff, err := cw.write(items)
+	if rejected, ok := rejectedOf(err); ok {
+		cw.rejected = rejected
+		return rowsAff, nil
+	}
+	return rowsAff, err
+}
+
+// takeRejected returns the items rejected by the last write
+func (cw *chunkWriter[R]) takeRejected() *RejectedError {
+	rejected := cw.rejected
+	cw.rejected = nil
+	return rejected
+}
+
+func (cw *chunkWriter[R]) replay() error {
+	for _, chunk := range cw.pending {
+		// the rejected items of the chunk are already skipped
+		if _, err := cw.write(chunk); err != nil {
+			if _, ok := rejectedOf(err); !ok {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (cw *chunkWriter[R]) rollback(err error) error {
+	if rbErr := cw.committer.Rollback(); rbErr != nil {
+		log.Err(rbErr).Msg("failed to rollback")
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package step
+
+import (
+	"fmt"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// FaultTolerance is the policy of a step for the items that failed to be read, processed or written
+// The zero value fails the partition at the first error
+type FaultTolerance struct {
+	SkipLimit    int64         // maximum number of items skipped in a partition
+	SkipKinds    []er.Kind     // kinds of the errors that can be skipped. read and process errors are er.KindInvalidItem unless the kind is set
+	RetryLimit   int           // number of retries of Processor.Process and Writer.Write before skipping or failing
+	RetryBackoff time.Duration // wait before the first retry. it is doubled on every retry
+	DeadLetter   DeadLetter    // receives the skipped items. can be nil
+}
+
+func (ft FaultTolerance) skippable(err error) bool {
+	for _, k := range ft.SkipKinds {
+		if er.IsKind(err, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// RejectedError is returned by Writer that wrote a chunk except the items rejected by the storage (example. the items in the errors of an API response)
+// The step counts the others as written and skips the rejected ones by FaultTolerance with Err, so Err needs the kind of SkipKinds
+type RejectedError struct {
+	Count   int   // number of the rejected items
+	Indexes []int // indexes of the rejected items in the chunk. nil if the writer cannot tell them
+	Err     error
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%d items rejected: %v", e.Count, e.Err)
+}
+
+// rejectedOf returns RejectedError of err wrapped by er or not
+func rejectedOf(err error) (*RejectedError, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var rejected *RejectedError
+	if errors.As(er.Parse(err).Err, &rejected) {
+		return rejected, true
+	}
+	return nil, false
+}
+
+// DeadLetter receives the items skipped by FaultTolerance
+// item is nil when the item failed to be read or the writer cannot tell the rejected item. see RejectedError
+type DeadLetter interface {
+	Write(partitionName string, item any, err error) error
+}
+
+type logDeadLetter struct{}
+
+// NewLogDeadLetter returns DeadLetter that logs the skipped items
+func NewLogDeadLetter() DeadLetter {
+	return logDeadLetter{}
+}
+
+func (ldl logDeadLetter) Write(partitionName string, item any, err error) error {
+	log.Warn().Err(err).Interface("item", item).Msgf("[%s] skipped item", partitionName)
+	return nil
+}
@@ -3,9 +3,11 @@ package step
 import (
 	"context"
 	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/util"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
-	"sync/atomic"
+	"github.com/rs/zerolog/log"
+	"io"
 )
 
 const LogIntervalSize = 30000
@@ -20,6 +22,9 @@ var EmptyDocProcessorParam EmptyDocProcessorParamType
 
 type step[T DocProcessor[R, J], R, K any, J ProcessorParam[J]] struct {
 	chunkSize      int64
+	faultTolerance FaultTolerance
+	pipeline       Pipeline
+	commitInterval int
 	reader         Reader[T, R, J]
 	processorParam ProcessorParam[J]
 	processor      Processor[T, R, J]
@@ -47,17 +52,100 @@ func NewStep[T DocProcessor[R, J], R, K any, J ProcessorParam[J]](
 	}
 }
 
+// NewStepWithOption is NewStep that takes the chunk size from opt
+// FaultTolerance, Pipeline and the commit interval are taken only if opt implements
+// FaultToleranceOption, PipelineOption and CommitIntervalOption
+func NewStepWithOption[T DocProcessor[R, J], R, K any, J ProcessorParam[J]](
+	opt Option,
+	reader Reader[T, R, J],
+	processorParam ProcessorParam[J],
+	processor Processor[T, R, J],
+	writer Writer[R, K]) Step {
+	s := &step[T, R, K, J]{
+		chunkSize:      opt.ChunkSize(),
+		reader:         reader,
+		processorParam: processorParam,
+		processor:      processor,
+		writer:         writer,
+	}
+	if o, ok := opt.(FaultToleranceOption); ok {
+		s.faultTolerance = o.FaultTolerance()
+	}
+	if o, ok := opt.(PipelineOption); ok {
+		s.pipeline = o.Pipeline()
+	}
+	if o, ok := opt.(CommitIntervalOption); ok {
+		s.commitInterval = o.CommitInterval()
+	}
+	return s
+}
+
 func (s step[T, R, K, J]) Proceed(ctx context.Context, pCtx parallel.Partition, wm monitoring.WorkerMonitoring) error {
-	var rowAffectedCount, rowCount int64
+	return s.ProceedFrom(ctx, pCtx, wm, Checkpoint{}, nil)
+}
+
+// ProceedFrom resumes the partition from cp and saves the reader position into store after every written chunk
+// The reader must implement Positioner to resume or to save checkpoints. store can be nil
+func (s step[T, R, K, J]) ProceedFrom(ctx context.Context, pCtx parallel.Partition, wm monitoring.WorkerMonitoring, cp Checkpoint, store CheckpointStore) error {
+	if s.pipeline.enabled() {
+		return s.proceedPipelined(ctx, pCtx, wm, cp, store)
+	}
+
 	defer wm.Finish()
+	defer s.closeReader(pCtx)
 
 	op := er.GetOperator()
 	buf := make([]R, 0, s.chunkSize)
 
+	positioner, canPosition, err := s.resume(pCtx, cp)
+	if err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	saveCheckpoint := func() error {
+		if store == nil || !canPosition {
+			return nil
+		}
+		return store.SaveCheckpoint(pCtx.PartitionName(), positioner.Position())
+	}
+
+	cw := s.chunkWriter(ctx, pCtx)
+	defer cw.discard()
+
+	p := s.newProceeding(pCtx, wm, cw)
+
+	write := func(items []R) error {
+		committed, err := s.writeChunk(p, items)
+		if err != nil {
+			return err
+		}
+
+		// the checkpoint only advances after the chunks are committed
+		if !committed {
+			return nil
+		}
+		return saveCheckpoint()
+	}
+
+	// cancelErr is set when ctx is cancelled. The items already read are written before returning it
+	var cancelErr error
+
 	for {
+		if err := ctx.Err(); err != nil {
+			cancelErr = err
+			break
+		}
+
 		item, done, err := s.reader.Read(ctx, pCtx)
 		if err != nil {
-			return er.WrapOp(err, op)
+			if ctx.Err() != nil {
+				cancelErr = ctx.Err()
+				break
+			}
+			if err := p.skip(nil, err); err != nil {
+				return er.WrapOp(err, op)
+			}
+			continue
 		}
 
 		if done {
@@ -65,17 +153,17 @@ func (s step[T, R, K, J]) Proceed(ctx context.Context, pCtx parallel.Partition,
 		}
 
 		if item != nil {
-			param, err := s.processorParam.GetProcessorParam()
-			if err != nil {
-				return er.WrapOp(err, op)
-			}
+			p.read()
 
-			refineItem, err := s.processor.Process(*item, param, pCtx)
+			refineItem, err := s.process(*item, pCtx)
 			if err != nil {
-				return er.WrapOp(err, op)
+				if err := p.skip(*item, err); err != nil {
+					return er.WrapOp(err, op)
+				}
+			} else {
+				p.processed()
+				buf = append(buf, *refineItem)
 			}
-
-			buf = append(buf, *refineItem)
 		}
 
 		if int64(len(buf)) >= s.chunkSize {
@@ -83,32 +171,148 @@ func (s step[T, R, K, J]) Proceed(ctx context.Context, pCtx parallel.Partition,
 			copy(copyBuf, buf)
 			buf = make([]R, 0, s.chunkSize)
 
-			rowsAff, err := s.writer.Write(copyBuf, pCtx)
-			if err != nil {
+			if err := write(copyBuf); err != nil {
 				return er.WrapOp(err, op)
 			}
+		}
+	}
 
-			atomic.AddInt64(&rowCount, s.chunkSize)
-			atomic.AddInt64(&rowAffectedCount, rowsAff)
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+		return write(buf)
+	}
 
-			if rowCount/LogIntervalSize > 0 && rowCount%LogIntervalSize == 0 {
-				wm.Send(monitoring.NewRowCountLog(pCtx.PartitionName(), rowAffectedCount, rowCount))
-				rowCount, rowAffectedCount = 0, 0
-			}
+	if err := s.finish(p, flush, saveCheckpoint, cancelErr); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+// closeReader closes the cursor of the reader (see reader.NewCursorReader) when the partition ends or fails
+func (s step[T, R, K, J]) closeReader(pCtx parallel.Partition) {
+	if c, ok := s.reader.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Err(err).Msgf("[%s] failed to close reader", pCtx.PartitionName())
 		}
 	}
+}
 
-	if len(buf) > 0 {
-		rowsAff, err := s.writer.Write(buf, pCtx)
+// complete completes the output of the partition if the writer is Completer
+func (s step[T, R, K, J]) complete(pCtx parallel.Partition) error {
+	if c, ok := s.writer.(Completer); ok {
+		return c.Complete(pCtx)
+	}
+	return nil
+}
+
+// write writes items and returns the affected and written count and whether they are committed
+// When the chunk fails with the skippable error, it writes the items one by one to pass only the failed ones to skip
+// The items rejected by the writer are passed to skip too. see RejectedError
+func (s step[T, R, K, J]) write(cw *chunkWriter[R], items []R, pCtx parallel.Partition, skip func(any, error) error) (int64, int64, bool, error) {
+	rowsAff, committed, err := cw.writeChunk(items)
+	if err == nil {
+		skipped, err := s.skipRejected(cw, items, pCtx, skip)
 		if err != nil {
-			return er.WrapOp(err, op)
+			return 0, 0, false, err
 		}
+		return rowsAff, int64(len(items)) - skipped, committed, nil
+	}
 
-		atomic.AddInt64(&rowCount, int64(len(buf)))
-		atomic.AddInt64(&rowAffectedCount, rowsAff)
+	if !s.faultTolerance.skippable(err) {
+		return 0, 0, false, err
 	}
 
-	wm.Send(monitoring.NewRowCountLog(pCtx.PartitionName(), rowAffectedCount, rowCount))
+	// the chunks rolled back with the failed one are committed before writing the items one by one
+	if err := cw.commit(true); err != nil {
+		return 0, 0, false, err
+	}
 
-	return nil
+	if len(items) == 1 {
+		return 0, 0, true, skip(items[0], err)
+	}
+
+	log.Warn().Err(err).Msgf("[%s] chunk failed. write %d items one by one", pCtx.PartitionName(), len(items))
+
+	var affected, written int64
+	for _, item := range items {
+		aff, committed, err := cw.writeCommit([]R{item}, true)
+		if err != nil {
+			if !s.faultTolerance.skippable(err) {
+				return affected, written, false, err
+			}
+			if err := skip(item, err); err != nil {
+				return affected, written, false, err
+			}
+			continue
+		}
+
+		skipped, err := s.skipRejected(cw, []R{item}, pCtx, skip)
+		if err != nil {
+			return affected, written, false, err
+		}
+		if committed {
+			affected += aff
+			written += 1 - skipped
+		}
+	}
+
+	return affected, written, true, nil
+}
+
+// skipRejected passes the items of the last write rejected by the writer to skip and returns the number of them
+func (s step[T, R, K, J]) skipRejected(cw *chunkWriter[R], items []R, pCtx parallel.Partition, skip func(any, error) error) (int64, error) {
+	rejected := cw.takeRejected()
+	if rejected == nil || rejected.Count <= 0 {
+		return 0, nil
+	}
+
+	log.Warn().Err(rejected.Err).Msgf("[%s] %d of %d items were rejected", pCtx.PartitionName(), rejected.Count, len(items))
+
+	count := rejected.Count
+	if count > len(items) {
+		count = len(items)
+	}
+
+	for i := 0; i < count; i++ {
+		var item any
+		if i < len(rejected.Indexes) && rejected.Indexes[i] >= 0 && rejected.Indexes[i] < len(items) {
+			item = items[rejected.Indexes[i]]
+		}
+		if err := skip(item, rejected.Err); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(count), nil
+}
+
+// chunkWriter returns chunkWriter of the writer of the partition
+func (s step[T, R, K, J]) chunkWriter(ctx context.Context, pCtx parallel.Partition) *chunkWriter[R] {
+	write := func(items []R) (int64, error) {
+		return s.writer.Write(items, pCtx)
+	}
+	retry := s.retry
+
+	if cw, ok := s.writer.(ContextWriter[R]); ok {
+		write = func(items []R) (int64, error) {
+			return cw.WriteContext(ctx, items, pCtx)
+		}
+		retry = func(f func() error) error {
+			return util.RetryBackoffContext(ctx, s.faultTolerance.RetryLimit+1, s.faultTolerance.RetryBackoff, f)
+		}
+	}
+	if r, ok := s.writer.(Retrier); ok && r.Retries() {
+		retry = func(f func() error) error {
+			return f()
+		}
+	}
+
+	return newChunkWriter[R](write, retry, s.writer, s.commitInterval)
+}
+
+func (s step[T, R, K, J]) retry(f func() error) error {
+	return util.RetryBackoff(s.faultTolerance.RetryLimit+1, s.faultTolerance.RetryBackoff, f)
 }
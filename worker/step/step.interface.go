@@ -11,6 +11,7 @@ type WriterStorage[K any] interface {
 	GetClient() *K
 }
 
+// ReaderDB is the database of the sqlx readers. The other storages implement Source
 type ReaderDB interface {
 	parallel.ParallelDB
 	GetReadQuery(string) string // Must returns Where range <= and >=
@@ -21,12 +22,50 @@ type Step interface {
 	Proceed(context.Context, parallel.Partition, monitoring.WorkerMonitoring) error
 }
 
+// RestartableStep is Step that can resume a partition from the last saved Checkpoint
+type RestartableStep interface {
+	Step
+	ProceedFrom(context.Context, parallel.Partition, monitoring.WorkerMonitoring, Checkpoint, CheckpointStore) error
+}
+
+// Checkpoint is the reader position up to which every item of the partition has been written
+type Checkpoint struct {
+	Page   int64 `json:"page"`
+	Offset int64 `json:"offset"`        // number of items already read in Page
+	Key    int64 `json:"key,omitempty"` // sort key of the last item read by the keyset reader
+}
+
+// Positioner is implemented by the reader that can report and restore its position
+type Positioner interface {
+	Position() Checkpoint
+	Seek(Checkpoint)
+}
+
+type CheckpointStore interface {
+	SaveCheckpoint(partitionName string, cp Checkpoint) error
+}
+
 type Option interface {
 	PageSize() int64
 	ChunkSize() int64
 	ReaderType() readerType
 }
 
+// FaultToleranceOption is implemented by the Option that sets FaultTolerance of the step
+type FaultToleranceOption interface {
+	FaultTolerance() FaultTolerance
+}
+
+// PipelineOption is implemented by the Option that sets Pipeline of the step
+type PipelineOption interface {
+	Pipeline() Pipeline
+}
+
+// CommitIntervalOption is implemented by the Option that sets the commit interval of the step
+type CommitIntervalOption interface {
+	CommitInterval() int
+}
+
 type NewReader[T DocProcessor[R, J], R any, J ProcessorParam[J]] interface {
 	New() Reader[T, R, J]
 }
@@ -36,6 +75,21 @@ type Reader[T DocProcessor[R, J], R any, J ProcessorParam[J]] interface {
 	Read(context.Context, parallel.Partition) (*T, bool, error)
 }
 
+// Source is the storage-agnostic contract of the readers (example. sqlx, files, HTTP APIs)
+// It opens a Cursor over the items of a partition. reader.NewCursorReader makes Reader of it
+type Source[T any] interface {
+	Open(context.Context, parallel.Partition) (Cursor[T], error)
+}
+
+// Cursor reads the items of a partition opened by Source
+// A Cursor implementing Positioner is sought to the checkpoint before the first Next. The others skip the items already read
+type Cursor[T any] interface {
+	// Next returns the next item. The nil item without error is ignored, and true is the end of the partition
+	Next(context.Context) (*T, bool, error)
+	// Close releases the cursor. It can be called after the end of the partition
+	Close() error
+}
+
 type ProcessorParam[J any] interface {
 	GetProcessorParam() (*J, error)
 }
@@ -44,6 +98,16 @@ type Processor[T DocProcessor[R, J], R any, J ProcessorParam[J]] interface {
 	Process(T, *J, parallel.Partition) (*R, error)
 }
 
+// SortKeyer is implemented by T read by the keyset reader to return the sort key of the item
+type SortKeyer interface {
+	SortKey() int64
+}
+
+// CountryCodeParam can be implemented by the processor param to receive the country code of the indexer worker
+type CountryCodeParam interface {
+	SetCountryCode(string)
+}
+
 type DocProcessor[R any, J ProcessorParam[J]] interface {
 	ToModel(*J) (*R, error)
 }
@@ -51,3 +115,15 @@ type DocProcessor[R any, J ProcessorParam[J]] interface {
 type Writer[R, K any] interface {
 	Write([]R, parallel.Partition) (int64, error)
 }
+
+// ContextWriter is Writer that stops writing when the context of the partition is cancelled (example. the requests in flight and their backoff)
+// The step calls WriteContext instead of Write. The chunk in progress when the job is cancelled fails and is not checkpointed
+type ContextWriter[R any] interface {
+	WriteContext(context.Context, []R, parallel.Partition) (int64, error)
+}
+
+// Retrier is Writer that retries the failed writes by itself (example. rest.Policy of the HTTP writer)
+// The step doesn't retry the writes of Retrier returning true by FaultTolerance.RetryLimit, so the retries are not multiplied
+type Retrier interface {
+	Retries() bool
+}
@@ -8,14 +8,44 @@ const (
 
 	FullRead readerType = iota
 	PagingRead
+	KeysetRead
 )
 
 type readerType int64
 
 type stepOption struct {
-	pageSize   int64
-	chunkSize  int64
-	readerType readerType
+	pageSize       int64
+	chunkSize      int64
+	readerType     readerType
+	faultTolerance FaultTolerance
+	pipeline       Pipeline
+	commitInterval int
+}
+
+// NewOption returns Option with the reader type, the page size of the paging readers and the chunk size of the writer
+func NewOption(readerType readerType, pageSize, chunkSize int64) *stepOption {
+	return &stepOption{
+		readerType: readerType,
+		pageSize:   pageSize,
+		chunkSize:  chunkSize,
+	}
+}
+
+func (so *stepOption) WithFaultTolerance(ft FaultTolerance) *stepOption {
+	so.faultTolerance = ft
+	return so
+}
+
+// WithPipeline runs the reader, the processors and the writer concurrently. see Pipeline
+func (so *stepOption) WithPipeline(p Pipeline) *stepOption {
+	so.pipeline = p
+	return so
+}
+
+// WithCommitInterval commits the transaction of Committer every n chunks. Every chunk is committed if n is not set
+func (so *stepOption) WithCommitInterval(n int) *stepOption {
+	so.commitInterval = n
+	return so
 }
 
 func (so *stepOption) PageSize() int64 {
@@ -30,6 +60,18 @@ func (so *stepOption) ReaderType() readerType {
 	return so.readerType
 }
 
+func (so *stepOption) FaultTolerance() FaultTolerance {
+	return so.faultTolerance
+}
+
+func (so *stepOption) Pipeline() Pipeline {
+	return so.pipeline
+}
+
+func (so *stepOption) CommitInterval() int {
+	return so.commitInterval
+}
+
 var (
 	DefaultPagingConsumerOption Option = &defaultStepOptions{
 		readerType: PagingRead,
@@ -53,6 +95,18 @@ var (
 		chunkSize:  defaultIndexerChunkSize,
 	}
 
+	DefaultKeysetConsumerOption Option = &defaultStepOptions{
+		readerType: KeysetRead,
+		pageSize:   defaultConsumerPageSize,
+		chunkSize:  defaultConsumerChunkSize,
+	}
+
+	DefaultKeysetIndexerOption Option = &defaultStepOptions{
+		readerType: KeysetRead,
+		pageSize:   defaultIndexerPageSize,
+		chunkSize:  defaultIndexerChunkSize,
+	}
+
 	ArticleConsumerOption Option = &defaultStepOptions{
 		readerType: PagingRead,
 		pageSize:   defaultConsumerPageSize,
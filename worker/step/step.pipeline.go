@@ -0,0 +1,327 @@
+package step
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"sync"
+)
+
+// Pipeline runs the reader, the processors and the writer of a step in their own goroutines
+// The zero value runs them one after another in a goroutine
+type Pipeline struct {
+	Processors int  // number of goroutines processing the items read
+	Buffer     int  // number of items read ahead of the writer. it is the chunk size if not set
+	Ordered    bool // keeps the order of the items read in the chunks. the items are written as they are processed if false
+}
+
+func (p Pipeline) enabled() bool {
+	return p.Processors > 0
+}
+
+// pipelineItem is an item passed from the reader to the writer with its sequence number
+// pos is the reader position right after the item is read
+type pipelineItem[T, R any] struct {
+	seq  int64
+	item *T
+	out  *R // nil if the item is skipped
+	pos  Checkpoint
+}
+
+// proceedPipelined is ProceedFrom of the pipelined step
+// The reader is bounded by Pipeline.Buffer items not written yet, and the checkpoint only covers the items whose preceding items are all committed
+func (s step[T, R, K, J]) proceedPipelined(ctx context.Context, pCtx parallel.Partition, wm monitoring.WorkerMonitoring, cp Checkpoint, store CheckpointStore) error {
+	defer wm.Finish()
+	defer s.closeReader(pCtx)
+
+	op := er.GetOperator()
+
+	positioner, canPosition, err := s.resume(pCtx, cp)
+	if err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	buffer := s.pipeline.Buffer
+	if buffer <= 0 {
+		buffer = int(s.chunkSize)
+	}
+
+	cw := s.chunkWriter(ctx, pCtx)
+	defer cw.discard()
+
+	p := s.newProceeding(pCtx, wm, cw)
+
+	// failed is closed at the first error to stop every goroutine
+	failed := make(chan struct{})
+	var firstErr error
+	var failOnce sync.Once
+
+	readCtx, cancelRead := context.WithCancel(ctx)
+
+	// the reader is closed after the reader goroutine exits
+	readDone := make(chan struct{})
+	defer func() {
+		cancelRead()
+		<-readDone
+	}()
+
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			close(failed)
+			cancelRead()
+		})
+	}
+
+	// inflight is taken by the reader for every item and given back when the writer receives it in order
+	inflight := make(chan struct{}, buffer+s.pipeline.Processors)
+	items := make(chan pipelineItem[T, R], buffer)
+	results := make(chan pipelineItem[T, R], buffer)
+
+	// cancelErr is set when ctx is cancelled. The items already read are written before returning it
+	var cancelErr error
+
+	go func() {
+		defer close(readDone)
+		defer close(items)
+
+		for seq := int64(0); ; seq++ {
+			if err := ctx.Err(); err != nil {
+				cancelErr = err
+				return
+			}
+
+			select {
+			case inflight <- struct{}{}:
+			case <-failed:
+				return
+			}
+
+			item, done, err := s.reader.Read(readCtx, pCtx)
+			if err != nil {
+				select {
+				case <-failed:
+					return
+				default:
+				}
+				if ctx.Err() != nil {
+					cancelErr = ctx.Err()
+					return
+				}
+				if err := p.skip(nil, err); err != nil {
+					fail(err)
+					return
+				}
+			}
+
+			if done {
+				return
+			}
+
+			pi := pipelineItem[T, R]{seq: seq, item: item}
+			if canPosition {
+				pi.pos = positioner.Position()
+			}
+
+			if item != nil {
+				p.read()
+			}
+
+			select {
+			case items <- pi:
+			case <-failed:
+				return
+			}
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < s.pipeline.Processors; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			for pi := range items {
+				if pi.item != nil {
+					out, err := s.process(*pi.item, pCtx)
+					if err != nil {
+						if err := p.skip(*pi.item, err); err != nil {
+							fail(err)
+							return
+						}
+					} else {
+						p.processed()
+						pi.out = out
+					}
+				}
+
+				select {
+				case results <- pi:
+				case <-failed:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	// written tracks the items whose preceding items are all committed to save the checkpoint
+	written := newWatermark()
+	pending := make(map[int64]pipelineItem[T, R])
+	next := int64(0)
+
+	buf := make([]R, 0, s.chunkSize)
+	seqs := make([]int64, 0, s.chunkSize)
+
+	// uncommitted is the items written but not committed yet
+	var uncommitted []int64
+
+	committed := func() error {
+		for _, seq := range uncommitted {
+			written.done(seq)
+		}
+		uncommitted = nil
+
+		if pos, ok := written.advance(); ok && store != nil && canPosition {
+			return store.SaveCheckpoint(pCtx.PartitionName(), pos)
+		}
+
+		return nil
+	}
+
+	write := func() error {
+		commit, err := s.writeChunk(p, buf)
+		if err != nil {
+			return err
+		}
+
+		uncommitted = append(uncommitted, seqs...)
+		buf, seqs = make([]R, 0, s.chunkSize), make([]int64, 0, s.chunkSize)
+
+		// the checkpoint only advances after the chunks are committed
+		if !commit {
+			return nil
+		}
+		return committed()
+	}
+
+	// add puts the item into the chunk and writes the chunk when it is full
+	add := func(pi pipelineItem[T, R]) error {
+		<-inflight
+
+		written.track(pi.seq, pi.pos)
+		if pi.out == nil {
+			written.done(pi.seq)
+			return nil
+		}
+
+		buf = append(buf, *pi.out)
+		seqs = append(seqs, pi.seq)
+
+		if int64(len(buf)) < s.chunkSize {
+			return nil
+		}
+		return write()
+	}
+
+	for pi := range results {
+		select {
+		case <-failed:
+			// drain the results until the processors exit
+			continue
+		default:
+		}
+
+		if !s.pipeline.Ordered {
+			if err := add(pi); err != nil {
+				fail(err)
+			}
+			continue
+		}
+
+		pending[pi.seq] = pi
+		for p, ok := pending[next]; ok; p, ok = pending[next] {
+			delete(pending, next)
+			next++
+			if err := add(p); err != nil {
+				fail(err)
+				break
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return er.WrapOp(firstErr, op)
+	}
+
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+		return write()
+	}
+
+	if err := s.finish(p, flush, committed, cancelErr); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+// process processes item with retries
+func (s step[T, R, K, J]) process(item T, pCtx parallel.Partition) (*R, error) {
+	param, err := s.processorParam.GetProcessorParam()
+	if err != nil {
+		return nil, err
+	}
+
+	var out *R
+	err = s.retry(func() (err error) {
+		out, err = s.processor.Process(item, param, pCtx)
+		return
+	})
+
+	return out, err
+}
+
+// watermark is the position of the last item whose preceding items are all done
+type watermark struct {
+	next      int64
+	positions map[int64]Checkpoint
+	finished  map[int64]bool
+}
+
+func newWatermark() *watermark {
+	return &watermark{
+		positions: make(map[int64]Checkpoint),
+		finished:  make(map[int64]bool),
+	}
+}
+
+func (w *watermark) track(seq int64, pos Checkpoint) {
+	w.positions[seq] = pos
+}
+
+func (w *watermark) done(seq int64) {
+	w.finished[seq] = true
+}
+
+// advance moves the watermark over the done items and returns the position of the last one
+func (w *watermark) advance() (Checkpoint, bool) {
+	var pos Checkpoint
+	var moved bool
+
+	for w.finished[w.next] {
+		pos, moved = w.positions[w.next], true
+		delete(w.finished, w.next)
+		delete(w.positions, w.next)
+		w.next++
+	}
+
+	return pos, moved
+}
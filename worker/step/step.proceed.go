@@ -0,0 +1,154 @@
+package step
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// proceeding is the state of a running partition shared by ProceedFrom and proceedPipelined
+type proceeding[R any] struct {
+	pCtx parallel.Partition
+	wm   monitoring.WorkerMonitoring
+	ft   FaultTolerance
+	cw   *chunkWriter[R]
+
+	// mu guards the counts and the dead letter shared by the goroutines of the pipeline
+	mu sync.Mutex
+	// stat is the count since the previous chunk passed to monitoring.Collector
+	stat                                                  monitoring.ChunkStat
+	rowAffectedCount, rowCount, skipCount, totalSkipCount int64
+}
+
+// resume seeks the reader to cp and returns the reader position to save checkpoints. It does nothing for the zero cp
+func (s step[T, R, K, J]) resume(pCtx parallel.Partition, cp Checkpoint) (Positioner, bool, error) {
+	positioner, canPosition := s.reader.(Positioner)
+
+	if cp == (Checkpoint{}) {
+		return positioner, canPosition, nil
+	}
+
+	if !canPosition {
+		return nil, false, errors.New("reader cannot resume from checkpoint")
+	}
+	if resumer, ok := s.writer.(Resumer); ok {
+		if err := resumer.Resume(pCtx, cp); err != nil {
+			return nil, false, err
+		}
+	}
+
+	positioner.Seek(cp)
+	log.Info().Msgf("[%s] resume from [page:%d] [offset:%d]", pCtx.PartitionName(), cp.Page, cp.Offset)
+
+	return positioner, true, nil
+}
+
+func (s step[T, R, K, J]) newProceeding(pCtx parallel.Partition, wm monitoring.WorkerMonitoring, cw *chunkWriter[R]) *proceeding[R] {
+	return &proceeding[R]{
+		pCtx: pCtx,
+		wm:   wm,
+		ft:   s.faultTolerance,
+		cw:   cw,
+	}
+}
+
+// skip returns err if the item cannot be skipped by FaultTolerance
+func (p *proceeding[R]) skip(item any, err error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.ft.skippable(err) || p.totalSkipCount >= p.ft.SkipLimit {
+		return err
+	}
+
+	if dl := p.ft.DeadLetter; dl != nil {
+		if err := dl.Write(p.pCtx.PartitionName(), item, err); err != nil {
+			return err
+		}
+	}
+
+	p.skipCount++
+	p.totalSkipCount++
+	p.stat.Skipped++
+	return nil
+}
+
+func (p *proceeding[R]) read() {
+	p.mu.Lock()
+	p.stat.Read++
+	p.mu.Unlock()
+}
+
+func (p *proceeding[R]) processed() {
+	p.mu.Lock()
+	p.stat.Processed++
+	p.mu.Unlock()
+}
+
+// writeChunk writes items, passes the stat of the chunk to monitoring.Collector and returns whether the chunks are committed
+func (s step[T, R, K, J]) writeChunk(p *proceeding[R], items []R) (bool, error) {
+	start := time.Now()
+
+	rowsAff, written, committed, err := s.write(p.cw, items, p.pCtx, p.skip)
+	if err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	p.rowCount += written
+	p.rowAffectedCount += rowsAff
+	p.stat.Written, p.stat.Affected, p.stat.Latency = written, rowsAff, time.Since(start)
+	chunk := p.stat
+	p.stat = monitoring.ChunkStat{}
+
+	// the counts are sent every LogIntervalSize rows
+	var counts monitoring.RowCountLog
+	if p.rowCount >= LogIntervalSize {
+		counts = monitoring.NewSkipCountLog(p.pCtx.PartitionName(), p.rowAffectedCount, p.rowCount, p.skipCount)
+		p.rowCount, p.rowAffectedCount, p.skipCount = 0, 0, 0
+	}
+	p.mu.Unlock()
+
+	p.wm.Collector().ChunkWritten(p.pCtx.PartitionName(), chunk)
+	if counts != nil {
+		p.wm.Send(counts)
+	}
+
+	return committed, nil
+}
+
+// finish writes the rest of the items with flush, commits the pending chunks and sends the counts of the partition
+// committed saves the checkpoint after the commit. The partition completes if it is not cancelled
+func (s step[T, R, K, J]) finish(p *proceeding[R], flush, committed func() error, cancelErr error) error {
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if len(p.cw.pending) > 0 {
+		if err := p.cw.commit(false); err != nil {
+			return err
+		}
+		if err := committed(); err != nil {
+			return err
+		}
+	}
+
+	if p.stat != (monitoring.ChunkStat{}) {
+		p.wm.Collector().ChunkWritten(p.pCtx.PartitionName(), p.stat)
+	}
+
+	p.wm.Send(monitoring.NewSkipCountLog(p.pCtx.PartitionName(), p.rowAffectedCount, p.rowCount, p.skipCount))
+
+	if p.totalSkipCount > 0 {
+		log.Warn().Msgf("[%s] skipped %d items", p.pCtx.PartitionName(), p.totalSkipCount)
+	}
+
+	if cancelErr != nil {
+		return cancelErr
+	}
+
+	return s.complete(p.pCtx)
+}
@@ -0,0 +1,620 @@
+package step
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Proceed(t *testing.T) {
+	t.Run("write every item in chunks", func(t *testing.T) {
+		w := &writerMock{}
+		s := NewStep[itemMock, int64, any, EmptyDocProcessorParamType](
+			3, &readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, wm))
+		assert.Equal(t, []int{3, 3, 3, 1}, w.chunks)
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(10), r.RowCount())
+		assert.True(t, wm.IsFinish())
+	})
+
+	t.Run("write items already read and stop when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		w := &writerMock{}
+		s := NewStep[itemMock, int64, any, EmptyDocProcessorParamType](
+			3, &readerMock{size: 10, cancelAt: 5, cancel: cancel}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		wm := monitoring.NewMonitoring(1)
+
+		err := s.Proceed(ctx, parallel.EmptyPartition, wm)
+
+		assert.Error(t, err)
+		assert.Equal(t, []int{3, 2}, w.chunks)
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(5), r.RowCount())
+	})
+
+	t.Run("resume from checkpoint and save checkpoint after every chunk", func(t *testing.T) {
+		w := &writerMock{}
+		s := NewStep[itemMock, int64, any, EmptyDocProcessorParamType](
+			3, &readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w).(RestartableStep)
+
+		store := &checkpointStoreMock{}
+
+		err := s.ProceedFrom(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1), Checkpoint{Offset: 4}, store)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 3}, w.chunks)
+		assert.Equal(t, []Checkpoint{{Offset: 7}, {Offset: 10}}, store.checkpoints)
+	})
+
+	t.Run("skip failed items into dead letter within skip limit", func(t *testing.T) {
+		w := &writerMock{rejects: map[int64]bool{5: true}}
+		dl := &deadLetterMock{}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithFaultTolerance(FaultTolerance{
+				SkipLimit:  2,
+				SkipKinds:  []er.Kind{er.KindInvalidItem, er.KindConflict},
+				RetryLimit: 1,
+				DeadLetter: dl,
+			}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{fails: map[itemMock]bool{2: true}}, w)
+
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, wm))
+		assert.Equal(t, []any{itemMock(2), int64(5)}, dl.items)
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(8), r.RowCount())
+		assert.Equal(t, int64(2), r.SkipCount())
+	})
+
+	t.Run("fail when items exceed skip limit", func(t *testing.T) {
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithFaultTolerance(FaultTolerance{
+				SkipLimit: 1,
+				SkipKinds: []er.Kind{er.KindInvalidItem},
+			}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{fails: map[itemMock]bool{2: true, 4: true}}, &writerMock{})
+
+		assert.Error(t, s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1)))
+	})
+
+	t.Run("skip the items rejected by the writer into dead letter", func(t *testing.T) {
+		w := &writerMock{drops: map[int64]bool{2: true, 8: true}}
+		dl := &deadLetterMock{}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithFaultTolerance(FaultTolerance{
+				SkipLimit:  2,
+				SkipKinds:  []er.Kind{er.KindInvalidItem},
+				DeadLetter: dl,
+			}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, wm))
+		assert.Equal(t, []int{3, 3, 3, 1}, w.chunks)
+		assert.Equal(t, []any{int64(2), int64(8)}, dl.items)
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(8), r.RowCount())
+		assert.Equal(t, int64(2), r.SkipCount())
+	})
+
+	t.Run("skip the rejected items without the indexes", func(t *testing.T) {
+		w := &writerMock{drops: map[int64]bool{2: true, 3: true}, countOnly: true}
+		dl := &deadLetterMock{}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithFaultTolerance(FaultTolerance{
+				SkipLimit:  2,
+				SkipKinds:  []er.Kind{er.KindInvalidItem},
+				DeadLetter: dl,
+			}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, wm))
+		assert.Equal(t, []any{nil, nil}, dl.items)
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(8), r.RowCount())
+		assert.Equal(t, int64(2), r.SkipCount())
+	})
+
+	t.Run("fail when the rejected items exceed skip limit", func(t *testing.T) {
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithFaultTolerance(FaultTolerance{
+				SkipLimit: 1,
+				SkipKinds: []er.Kind{er.KindInvalidItem},
+			}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, &writerMock{drops: map[int64]bool{2: true, 3: true}})
+
+		assert.Error(t, s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1)))
+	})
+
+	t.Run("not retry the writes of the writer retrying by itself", func(t *testing.T) {
+		w := &retrierWriterMock{writerMock{failAt: 1}}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithFaultTolerance(FaultTolerance{RetryLimit: 2}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		assert.Error(t, s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1)))
+		assert.Equal(t, 1, w.calls)
+	})
+
+	t.Run("stop at the first failed chunk", func(t *testing.T) {
+		w := &writerMock{failAt: 2}
+		s := NewStep[itemMock, int64, any, EmptyDocProcessorParamType](
+			3, &readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		err := s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1))
+
+		assert.Error(t, err)
+		assert.Equal(t, []int{3}, w.chunks)
+	})
+}
+
+func Test_NewStepWithOption(t *testing.T) {
+	t.Run("take the settings of the option", func(t *testing.T) {
+		ft := FaultTolerance{RetryLimit: 2}
+		p := Pipeline{Processors: 2}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithFaultTolerance(ft).WithPipeline(p).WithCommitInterval(2),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, &writerMock{}).(*step[itemMock, int64, any, EmptyDocProcessorParamType])
+
+		assert.Equal(t, int64(3), s.chunkSize)
+		assert.Equal(t, ft, s.faultTolerance)
+		assert.Equal(t, p, s.pipeline)
+		assert.Equal(t, 2, s.commitInterval)
+	})
+
+	t.Run("take only the chunk size of the option not implementing the optional settings", func(t *testing.T) {
+		w := &writerMock{}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			optionMock{chunkSize: 3}, &readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1)))
+		assert.Equal(t, []int{3, 3, 3, 1}, w.chunks)
+
+		st := s.(*step[itemMock, int64, any, EmptyDocProcessorParamType])
+		assert.Equal(t, FaultTolerance{}, st.faultTolerance)
+		assert.Equal(t, Pipeline{}, st.pipeline)
+		assert.Equal(t, 0, st.commitInterval)
+	})
+}
+
+func Test_ProceedResume(t *testing.T) {
+	t.Run("fail before reading when the writer cannot resume", func(t *testing.T) {
+		for _, p := range []Pipeline{{}, {Processors: 2}} {
+			r := &readerMock{size: 10}
+			w := &resumerWriterMock{err: errors.New("cannot resume")}
+			s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+				NewOption(PagingRead, 0, 3).WithPipeline(p), r, EmptyDocProcessorParam, &processorMock{}, w)
+
+			err := s.(RestartableStep).ProceedFrom(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1), Checkpoint{Offset: 3}, &checkpointStoreMock{})
+
+			assert.Error(t, err)
+			assert.Equal(t, []Checkpoint{{Offset: 3}}, w.resumed)
+			assert.Empty(t, w.items)
+		}
+	})
+
+	t.Run("not resume the writer of the partition from the beginning", func(t *testing.T) {
+		w := &resumerWriterMock{err: errors.New("cannot resume")}
+		s := NewStep[itemMock, int64, any, EmptyDocProcessorParamType](3, &readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		assert.NoError(t, s.(RestartableStep).ProceedFrom(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1), Checkpoint{}, &checkpointStoreMock{}))
+		assert.Empty(t, w.resumed)
+		assert.Len(t, w.items, 10)
+	})
+}
+
+func Test_ProceedPipelined(t *testing.T) {
+	pipelined := func(p Pipeline, ft FaultTolerance, r *readerMock, pm *processorMock, w *writerMock) Step {
+		return NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithPipeline(p).WithFaultTolerance(ft), r, EmptyDocProcessorParam, pm, w)
+	}
+
+	t.Run("write every item in order", func(t *testing.T) {
+		w := &writerMock{}
+		s := pipelined(Pipeline{Processors: 4, Buffer: 2, Ordered: true}, FaultTolerance{}, &readerMock{size: 100}, &processorMock{}, w)
+
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, wm))
+		assert.Len(t, w.items, 100)
+		for i, item := range w.items {
+			assert.Equal(t, int64(i+1), item)
+		}
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(100), r.RowCount())
+	})
+
+	t.Run("write every item once in relaxed order", func(t *testing.T) {
+		w := &writerMock{}
+		s := pipelined(Pipeline{Processors: 4}, FaultTolerance{}, &readerMock{size: 100}, &processorMock{}, w)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1)))
+		assert.ElementsMatch(t, seq(1, 100), w.items)
+	})
+
+	t.Run("save checkpoint of the items written with all preceding ones", func(t *testing.T) {
+		w := &writerMock{}
+		s := pipelined(Pipeline{Processors: 3}, FaultTolerance{}, &readerMock{size: 30}, &processorMock{}, w).(RestartableStep)
+
+		store := &checkpointStoreMock{writer: w}
+
+		assert.NoError(t, s.ProceedFrom(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1), Checkpoint{Offset: 4}, store))
+		assert.ElementsMatch(t, seq(5, 30), w.items)
+		assert.NotEmpty(t, store.checkpoints)
+		assert.Equal(t, Checkpoint{Offset: 30}, store.checkpoints[len(store.checkpoints)-1])
+
+		for i, cp := range store.checkpoints {
+			assert.Subset(t, store.written[i], seq(5, cp.Offset))
+		}
+	})
+
+	t.Run("skip failed items", func(t *testing.T) {
+		w := &writerMock{}
+		dl := &deadLetterMock{}
+		s := pipelined(Pipeline{Processors: 2, Ordered: true}, FaultTolerance{
+			SkipLimit:  1,
+			SkipKinds:  []er.Kind{er.KindInvalidItem},
+			DeadLetter: dl,
+		}, &readerMock{size: 10}, &processorMock{fails: map[itemMock]bool{4: true}}, w)
+
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, wm))
+		assert.Equal(t, []int64{1, 2, 3, 5, 6, 7, 8, 9, 10}, w.items)
+		assert.Equal(t, []any{itemMock(4)}, dl.items)
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(1), r.SkipCount())
+	})
+
+	t.Run("stop every goroutine at the first error", func(t *testing.T) {
+		w := &writerMock{failAt: 2}
+		s := pipelined(Pipeline{Processors: 4, Ordered: true}, FaultTolerance{}, &readerMock{size: 1000}, &processorMock{}, w)
+
+		err := s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1))
+
+		assert.Error(t, err)
+		assert.Equal(t, []int{3}, w.chunks)
+
+		err = pipelined(Pipeline{Processors: 4}, FaultTolerance{}, &readerMock{size: 1000}, &processorMock{fails: map[itemMock]bool{7: true}}, &writerMock{}).
+			Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("write items already read and stop when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		w := &writerMock{}
+		s := pipelined(Pipeline{Processors: 2, Ordered: true}, FaultTolerance{}, &readerMock{size: 100, cancelAt: 5, cancel: cancel}, &processorMock{}, w)
+
+		err := s.Proceed(ctx, parallel.EmptyPartition, monitoring.NewMonitoring(1))
+
+		assert.Error(t, err)
+		assert.Equal(t, seq(1, 5), w.items)
+	})
+}
+
+func seq(from, to int64) []int64 {
+	var s []int64
+	for i := from; i <= to; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+func Test_ProceedCommit(t *testing.T) {
+	t.Run("commit every interval and save checkpoint after commit", func(t *testing.T) {
+		w := &txWriterMock{}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithCommitInterval(2), &readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w).(RestartableStep)
+
+		store := &checkpointStoreMock{}
+
+		assert.NoError(t, s.ProceedFrom(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1), Checkpoint{}, store))
+		assert.Equal(t, seq(1, 10), w.committed)
+		assert.Equal(t, []int{6, 10}, w.commits)
+		assert.Equal(t, []Checkpoint{{Offset: 6}, {Offset: 10}}, store.checkpoints)
+		assert.Equal(t, 1, w.completes)
+	})
+
+	t.Run("roll back and write the uncommitted chunks again on retry", func(t *testing.T) {
+		w := &txWriterMock{failAt: map[int64]int{5: 1}}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithCommitInterval(2).WithFaultTolerance(FaultTolerance{RetryLimit: 1}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1)))
+		assert.Equal(t, seq(1, 10), w.committed)
+		assert.Equal(t, 1, w.rollbacks)
+	})
+
+	t.Run("roll back the uncommitted chunks when failed", func(t *testing.T) {
+		w := &txWriterMock{failAt: map[int64]int{8: 1}}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithCommitInterval(2), &readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w).(RestartableStep)
+
+		store := &checkpointStoreMock{}
+
+		assert.Error(t, s.ProceedFrom(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1), Checkpoint{}, store))
+		assert.Equal(t, seq(1, 6), w.committed)
+		assert.Empty(t, w.uncommitted)
+		assert.Equal(t, []Checkpoint{{Offset: 6}}, store.checkpoints)
+		assert.Zero(t, w.completes)
+	})
+
+	t.Run("skip failed item after committing the rolled back chunks", func(t *testing.T) {
+		w := &txWriterMock{failAt: map[int64]int{5: -1}}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithCommitInterval(3).WithFaultTolerance(FaultTolerance{
+				SkipLimit: 1,
+				SkipKinds: []er.Kind{er.KindConflict},
+			}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w)
+
+		assert.NoError(t, s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1)))
+		assert.Equal(t, []int64{1, 2, 3, 4, 6, 7, 8, 9, 10}, w.committed)
+	})
+
+	t.Run("commit every interval with the pipeline and save checkpoint after commit", func(t *testing.T) {
+		w := &txWriterMock{}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithCommitInterval(2).WithPipeline(Pipeline{Processors: 2, Ordered: true}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w).(RestartableStep)
+
+		store := &checkpointStoreMock{}
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.ProceedFrom(context.Background(), parallel.EmptyPartition, wm, Checkpoint{}, store))
+		assert.Equal(t, seq(1, 10), w.committed)
+		assert.Equal(t, []int{6, 10}, w.commits)
+		assert.Equal(t, []Checkpoint{{Offset: 6}, {Offset: 10}}, store.checkpoints)
+		assert.Equal(t, 1, w.completes)
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(10), r.RowCount())
+	})
+
+	t.Run("replay the uncommitted chunks with the pipeline and skip the failed item", func(t *testing.T) {
+		w := &txWriterMock{failAt: map[int64]int{5: 1, 8: -1}}
+		dl := &deadLetterMock{}
+		s := NewStepWithOption[itemMock, int64, any, EmptyDocProcessorParamType](
+			NewOption(PagingRead, 0, 3).WithCommitInterval(2).WithPipeline(Pipeline{Processors: 3, Ordered: true}).WithFaultTolerance(FaultTolerance{
+				SkipLimit:  1,
+				SkipKinds:  []er.Kind{er.KindConflict},
+				RetryLimit: 1,
+				DeadLetter: dl,
+			}),
+			&readerMock{size: 10}, EmptyDocProcessorParam, &processorMock{}, w).(RestartableStep)
+
+		store := &checkpointStoreMock{}
+		wm := monitoring.NewMonitoring(1)
+
+		assert.NoError(t, s.ProceedFrom(context.Background(), parallel.EmptyPartition, wm, Checkpoint{}, store))
+		assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 9, 10}, w.committed)
+		assert.Equal(t, []any{int64(8)}, dl.items)
+		assert.Equal(t, 1, w.rollbacks)
+		assert.Equal(t, Checkpoint{Offset: 10}, store.checkpoints[len(store.checkpoints)-1])
+
+		r := <-wm.ReceiveResult()
+		assert.Equal(t, int64(9), r.RowCount())
+		assert.Equal(t, int64(1), r.SkipCount())
+	})
+}
+
+// txWriterMock is Committer and Completer. failAt is the number of failures of the chunks containing the item. -1 fails always
+type txWriterMock struct {
+	failAt      map[int64]int
+	uncommitted []int64
+	committed   []int64
+	commits     []int
+	rollbacks   int
+	completes   int
+}
+
+func (w *txWriterMock) Write(items []int64, _ parallel.Partition) (int64, error) {
+	for _, item := range items {
+		if n := w.failAt[item]; n != 0 {
+			w.failAt[item] = n - 1
+			return 0, er.New("duplicated", "txWriterMock", er.KindConflict)
+		}
+	}
+
+	w.uncommitted = append(w.uncommitted, items...)
+	return int64(len(items)), nil
+}
+
+func (w *txWriterMock) Commit() error {
+	w.committed = append(w.committed, w.uncommitted...)
+	w.uncommitted = nil
+	w.commits = append(w.commits, len(w.committed))
+	return nil
+}
+
+func (w *txWriterMock) Rollback() error {
+	if len(w.uncommitted) > 0 {
+		w.rollbacks++
+	}
+	w.uncommitted = nil
+	return nil
+}
+
+func (w *txWriterMock) Complete(parallel.Partition) error {
+	w.completes++
+	return nil
+}
+
+// optionMock implements only Option
+type optionMock struct {
+	chunkSize int64
+}
+
+func (o optionMock) PageSize() int64 {
+	return 0
+}
+
+func (o optionMock) ChunkSize() int64 {
+	return o.chunkSize
+}
+
+func (o optionMock) ReaderType() readerType {
+	return FullRead
+}
+
+type itemMock int64
+
+func (i itemMock) ToModel(*EmptyDocProcessorParamType) (*int64, error) {
+	v := int64(i)
+	return &v, nil
+}
+
+type readerMock struct {
+	size     int64
+	read     int64
+	cancelAt int64
+	cancel   context.CancelFunc
+}
+
+func (r *readerMock) New() Reader[itemMock, int64, EmptyDocProcessorParamType] {
+	return &readerMock{size: r.size, cancelAt: r.cancelAt, cancel: r.cancel}
+}
+
+func (r *readerMock) Read(ctx context.Context, _ parallel.Partition) (*itemMock, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if r.read >= r.size {
+		return nil, true, nil
+	}
+
+	r.read++
+	if r.cancel != nil && r.read == r.cancelAt {
+		r.cancel()
+	}
+
+	item := itemMock(r.read)
+	return &item, false, nil
+}
+
+func (r *readerMock) Position() Checkpoint {
+	return Checkpoint{Offset: r.read}
+}
+
+func (r *readerMock) Seek(cp Checkpoint) {
+	r.read = cp.Offset
+}
+
+type checkpointStoreMock struct {
+	checkpoints []Checkpoint
+	writer      *writerMock
+	written     [][]int64 // items written by writer when each checkpoint is saved
+}
+
+func (s *checkpointStoreMock) SaveCheckpoint(_ string, cp Checkpoint) error {
+	s.checkpoints = append(s.checkpoints, cp)
+	if s.writer != nil {
+		s.written = append(s.written, append([]int64(nil), s.writer.items...))
+	}
+	return nil
+}
+
+type processorMock struct {
+	fails map[itemMock]bool
+}
+
+func (p *processorMock) Process(item itemMock, param *EmptyDocProcessorParamType, _ parallel.Partition) (*int64, error) {
+	if p.fails[item] {
+		return nil, er.New("invalid item", "processorMock", er.KindInvalidItem)
+	}
+	return item.ToModel(param)
+}
+
+type writerMock struct {
+	chunks    []int
+	items     []int64
+	failAt    int
+	rejects   map[int64]bool
+	drops     map[int64]bool // written except them with RejectedError
+	countOnly bool           // RejectedError without the indexes
+	calls     int
+}
+
+type resumerWriterMock struct {
+	writerMock
+	err     error
+	resumed []Checkpoint
+}
+
+func (w *resumerWriterMock) Resume(_ parallel.Partition, cp Checkpoint) error {
+	w.resumed = append(w.resumed, cp)
+	return w.err
+}
+
+type retrierWriterMock struct {
+	writerMock
+}
+
+func (w *retrierWriterMock) Retries() bool {
+	return true
+}
+
+func (w *writerMock) Write(items []int64, _ parallel.Partition) (int64, error) {
+	w.calls++
+	if w.failAt > 0 && len(w.chunks)+1 == w.failAt {
+		return 0, errors.New("write failed")
+	}
+
+	for _, item := range items {
+		if w.rejects[item] {
+			return 0, er.New("duplicated", "writerMock", er.KindConflict)
+		}
+	}
+
+	w.chunks = append(w.chunks, len(items))
+
+	rejected := &RejectedError{Err: er.New("rejected", "writerMock", er.KindInvalidItem)}
+	for i, item := range items {
+		if w.drops[item] {
+			rejected.Count++
+			if !w.countOnly {
+				rejected.Indexes = append(rejected.Indexes, i)
+			}
+			continue
+		}
+		w.items = append(w.items, item)
+	}
+	if rejected.Count > 0 {
+		return int64(len(items) - rejected.Count), rejected
+	}
+	return int64(len(items)), nil
+}
+
+type deadLetterMock struct {
+	items []any
+}
+
+func (d *deadLetterMock) Write(_ string, item any, _ error) error {
+	d.items = append(d.items, item)
+	return nil
+}
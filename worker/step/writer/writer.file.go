@@ -0,0 +1,311 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/mapper"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type FileFormat string
+
+const (
+	CsvFormat    FileFormat = "csv"    // header and columns of the db tags. see mapper.Tag
+	NdjsonFormat FileFormat = "ndjson" // a line of json per item
+)
+
+// partialSuffix is the suffix of the file written until the partition completes
+const partialSuffix = ".partial"
+
+// FileOption is the option of NewFileDocWriter
+type FileOption struct {
+	Format FileFormat
+	Gzip   bool   // compresses the file and appends ".gz" to the name
+	Prefix string // prefix of the file name (example. "faqs-" writes "faqs-pCtx0.csv")
+	Comma  rune   // field delimiter of CsvFormat. ',' if not set
+}
+
+// fileDocWriter writes the chunks of a partition into a file of dir named by parallel.Partition.PartitionName()
+// The chunks are buffered until the step commits them (see step.Committer), so a retried chunk is not written twice,
+// and the partial file is renamed when the partition completes (see step.Completer)
+type fileDocWriter[R, K any] struct {
+	dir string
+	opt FileOption
+
+	partition string // name of the partition of the open file
+	path      string
+	file      *os.File
+	created   bool // the partial file is created and opened again to append
+	gz        *gzip.Writer
+	out       *bufio.Writer
+
+	pending bytes.Buffer // chunks not committed yet
+	fields  []mapper.Field
+	values  []any
+	record  []string
+}
+
+// NewFileDocWriter returns the Writer of a file per partition. Create a writer per partition like worker does
+// A restarted partition writes its file from the beginning, so it fails to resume from the checkpoint of repository.JobRepository. see step.Resumer
+func NewFileDocWriter[R, K any](dir string, opt FileOption) step.Writer[R, K] {
+	return &fileDocWriter[R, K]{
+		dir: dir,
+		opt: opt,
+	}
+}
+
+// FileName returns the name of the file of the partition
+func (opt FileOption) FileName(partitionName string) string {
+	name := opt.Prefix + partitionName + "." + string(opt.Format)
+	if opt.Gzip {
+		name += ".gz"
+	}
+	return name
+}
+
+func (fdw *fileDocWriter[R, K]) Write(items []R, pCtx parallel.Partition) (int64, error) {
+	op := er.GetOperator()
+
+	if fdw.partition != "" && fdw.partition != pCtx.PartitionName() {
+		return 0, er.WrapOp(errors.Errorf("file of [%s] is not completed", fdw.partition), op)
+	}
+	fdw.partition = pCtx.PartitionName()
+
+	var err error
+	switch fdw.opt.Format {
+	case CsvFormat:
+		err = fdw.writeCsv(items)
+	case NdjsonFormat:
+		err = fdw.writeNdjson(items)
+	default:
+		return 0, er.WrapOp(errors.Errorf("unsupported file format [%s]", fdw.opt.Format), op)
+	}
+	if err != nil {
+		return 0, er.WrapOpAndKind(err, op, er.KindInvalidItem)
+	}
+
+	return int64(len(items)), nil
+}
+
+func (fdw *fileDocWriter[R, K]) writeCsv(items []R) error {
+	mapped := mapper.For[R]()
+	w := fdw.newCsv(&fdw.pending)
+
+	for _, item := range items {
+		fdw.values = mapped.Values(item, fdw.columns(), fdw.values[:0])
+
+		fdw.record = fdw.record[:0]
+		for i, v := range fdw.values {
+			text, err := mapper.Format(v)
+			if err != nil {
+				return errors.Wrapf(err, "column [%s]", fdw.fields[i].Name)
+			}
+			fdw.record = append(fdw.record, text)
+		}
+
+		if err := w.Write(fdw.record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func (fdw *fileDocWriter[R, K]) writeNdjson(items []R) error {
+	enc := json.NewEncoder(&fdw.pending)
+	for _, item := range items {
+		// Encode ends the item with a line break
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columns returns the fields of R written in CsvFormat
+func (fdw *fileDocWriter[R, K]) columns() []mapper.Field {
+	if fdw.fields == nil {
+		mapped := mapper.For[R]()
+		fdw.fields, _ = mapped.Select(mapped.Names())
+	}
+	return fdw.fields
+}
+
+func (fdw *fileDocWriter[R, K]) newCsv(w io.Writer) *csv.Writer {
+	c := csv.NewWriter(w)
+	if fdw.opt.Comma != 0 {
+		c.Comma = fdw.opt.Comma
+	}
+	return c
+}
+
+// Commit appends the pending chunks to the partial file
+func (fdw *fileDocWriter[R, K]) Commit() error {
+	op := er.GetOperator()
+
+	if fdw.pending.Len() == 0 {
+		return nil
+	}
+
+	if err := fdw.open(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if _, err := fdw.pending.WriteTo(fdw.out); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := fdw.flush(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+// Rollback discards the pending chunks and closes the partial file, so it is not left open by a failed partition
+func (fdw *fileDocWriter[R, K]) Rollback() error {
+	fdw.pending.Reset()
+
+	if fdw.file == nil {
+		return nil
+	}
+
+	// the file of a retried chunk is opened again to append. see open
+	if err := fdw.close(); err != nil {
+		return er.WrapOp(err, er.GetOperator())
+	}
+
+	return nil
+}
+
+// Resume fails because the partial file is created from the beginning and the chunks after the checkpoint may be in it
+func (fdw *fileDocWriter[R, K]) Resume(pCtx parallel.Partition, cp step.Checkpoint) error {
+	return er.WrapOp(errors.Errorf("file of [%s] cannot resume from [page:%d] [offset:%d]. delete the checkpoint to write it again",
+		pCtx.PartitionName(), cp.Page, cp.Offset), er.GetOperator())
+}
+
+// Complete renames the partial file of the partition. The partition without items has the file without rows
+func (fdw *fileDocWriter[R, K]) Complete(pCtx parallel.Partition) error {
+	op := er.GetOperator()
+
+	fdw.partition = pCtx.PartitionName()
+
+	if err := fdw.Commit(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := fdw.open(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := fdw.close(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	if err := os.Rename(fdw.path+partialSuffix, fdw.path); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	log.Info().Msgf("[%s] wrote file [%s]", fdw.partition, fdw.path)
+	fdw.partition, fdw.path, fdw.created = "", "", false
+
+	return nil
+}
+
+// open creates the partial file of the partition if it is not open, or appends to it after a rollback
+// The gzip file appended has a gzip member per open, which gzip.Reader reads as one stream
+func (fdw *fileDocWriter[R, K]) open() error {
+	if fdw.file != nil {
+		return nil
+	}
+
+	if fdw.created {
+		f, err := os.OpenFile(fdw.path+partialSuffix, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		fdw.setFile(f)
+		return nil
+	}
+
+	if err := os.MkdirAll(fdw.dir, 0755); err != nil {
+		return err
+	}
+
+	fdw.path = filepath.Join(fdw.dir, fdw.opt.FileName(fdw.partition))
+
+	f, err := os.Create(fdw.path + partialSuffix)
+	if err != nil {
+		return err
+	}
+	fdw.setFile(f)
+	fdw.created = true
+
+	if fdw.opt.Format != CsvFormat {
+		return nil
+	}
+
+	header := make([]string, 0, len(fdw.columns()))
+	for _, field := range fdw.columns() {
+		header = append(header, field.Name)
+	}
+
+	c := fdw.newCsv(fdw.out)
+	if err := c.Write(header); err != nil {
+		return err
+	}
+	c.Flush()
+	return c.Error()
+}
+
+func (fdw *fileDocWriter[R, K]) setFile(f *os.File) {
+	fdw.file = f
+
+	var w io.Writer = f
+	if fdw.opt.Gzip {
+		fdw.gz = gzip.NewWriter(f)
+		w = fdw.gz
+	}
+	fdw.out = bufio.NewWriter(w)
+}
+
+func (fdw *fileDocWriter[R, K]) flush() error {
+	if err := fdw.out.Flush(); err != nil {
+		return err
+	}
+	if fdw.gz != nil {
+		return fdw.gz.Flush()
+	}
+	return nil
+}
+
+func (fdw *fileDocWriter[R, K]) close() error {
+	f := fdw.file
+	fdw.file = nil
+
+	err := fdw.out.Flush()
+	if err == nil && fdw.gz != nil {
+		err = fdw.gz.Close()
+	}
+	fdw.gz = nil
+
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
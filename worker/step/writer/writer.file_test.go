@@ -0,0 +1,122 @@
+package writer
+
+import (
+	"compress/gzip"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fileDocMock struct {
+	Id    int64   `db:"id" json:"id"`
+	Title string  `db:"title" json:"title"`
+	Score *string `db:"score" json:"score,omitempty"`
+}
+
+func Test_FileDocWriter(t *testing.T) {
+	pCtx := parallel.RestorePartition("pCtx3", 1, 100, 0, parallel.AutoIncrementIdType)
+
+	t.Run("write the committed chunks and rename the file on completion", func(t *testing.T) {
+		dir := t.TempDir()
+		w := NewFileDocWriter[fileDocMock, any](dir, FileOption{Format: CsvFormat, Prefix: "faqs-"})
+		committer := w.(step.Committer)
+
+		_, err := w.Write([]fileDocMock{{Id: 1, Title: "a"}, {Id: 2, Title: "b,c"}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, committer.Commit())
+
+		// the retried chunk is written once
+		_, err = w.Write([]fileDocMock{{Id: 3, Title: "d"}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, committer.Rollback())
+		_, err = w.Write([]fileDocMock{{Id: 3, Title: "d"}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, committer.Commit())
+
+		path := filepath.Join(dir, "faqs-pCtx3.csv")
+		assert.NoFileExists(t, path)
+		assert.FileExists(t, path+partialSuffix)
+
+		assert.NoError(t, w.(step.Completer).Complete(pCtx))
+		assert.NoFileExists(t, path+partialSuffix)
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "id,title,score\n1,a,\n2,\"b,c\",\n3,d,\n", string(content))
+	})
+
+	t.Run("compress ndjson with gzip", func(t *testing.T) {
+		dir := t.TempDir()
+		w := NewFileDocWriter[fileDocMock, any](dir, FileOption{Format: NdjsonFormat, Gzip: true})
+
+		_, err := w.Write([]fileDocMock{{Id: 1, Title: "a"}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.(step.Completer).Complete(pCtx))
+
+		f, err := os.Open(filepath.Join(dir, "pCtx3.ndjson.gz"))
+		assert.NoError(t, err)
+		defer f.Close()
+
+		gz, err := gzip.NewReader(f)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"id\":1,\"title\":\"a\"}\n", string(content))
+	})
+
+	t.Run("write the header for the empty partition", func(t *testing.T) {
+		dir := t.TempDir()
+		w := NewFileDocWriter[fileDocMock, any](dir, FileOption{Format: CsvFormat, Comma: '\t'})
+
+		assert.NoError(t, w.(step.Completer).Complete(pCtx))
+
+		content, err := os.ReadFile(filepath.Join(dir, "pCtx3.csv"))
+		assert.NoError(t, err)
+		assert.Equal(t, "id\ttitle\tscore\n", string(content))
+	})
+
+	t.Run("close the file on rollback and append to it on the next commit", func(t *testing.T) {
+		dir := t.TempDir()
+		w := NewFileDocWriter[fileDocMock, any](dir, FileOption{Format: NdjsonFormat, Gzip: true})
+		committer := w.(step.Committer)
+
+		_, err := w.Write([]fileDocMock{{Id: 1, Title: "a"}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, committer.Commit())
+
+		assert.NoError(t, committer.Rollback())
+		assert.Nil(t, w.(*fileDocWriter[fileDocMock, any]).file)
+
+		_, err = w.Write([]fileDocMock{{Id: 2, Title: "b"}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.(step.Completer).Complete(pCtx))
+
+		f, err := os.Open(filepath.Join(dir, "pCtx3.ndjson.gz"))
+		assert.NoError(t, err)
+		defer f.Close()
+
+		gz, err := gzip.NewReader(f)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"id\":1,\"title\":\"a\"}\n{\"id\":2,\"title\":\"b\"}\n", string(content))
+	})
+
+	t.Run("fail to resume without truncating the partial file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "pCtx3.csv"+partialSuffix)
+		assert.NoError(t, os.WriteFile(path, []byte("id,title,score\n1,a,\n"), 0644))
+
+		w := NewFileDocWriter[fileDocMock, any](dir, FileOption{Format: CsvFormat})
+
+		assert.Error(t, w.(step.Resumer).Resume(pCtx, step.Checkpoint{Offset: 1}))
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "id,title,score\n1,a,\n", string(content))
+	})
+}
@@ -0,0 +1,208 @@
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/rest"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+)
+
+type BodyFormat string
+
+const (
+	JsonArrayBody BodyFormat = "json"   // json array of the chunk
+	NdjsonBody    BodyFormat = "ndjson" // a line of json per item
+)
+
+// HttpOption is the option of NewHttpDocWriter
+type HttpOption struct {
+	Client        *http.Client // http.DefaultClient if nil
+	Method        string       // http.MethodPost if not set
+	Header        http.Header  // headers of every request (example. Authorization)
+	Format        BodyFormat   // JsonArrayBody if not set
+	Gzip          bool         // compresses the body with Content-Encoding gzip
+	RejectedField string       // dotted path of the rejected items or the number of them in the response (example. "errors")
+	IndexField    string       // dotted path of the index of the item in a rejected item (example. "index"). the rejected items of numbers are the indexes
+	rest.Policy                // Concurrency limits the requests of all the partitions writing to the host
+}
+
+type httpDocWriter[R, K any] struct {
+	endpoint string
+	opt      HttpOption
+}
+
+// NewHttpDocWriter returns the Writer sending every chunk in a request to endpoint
+// The transient failures (408, 429, 5xx) are retried by rest.Policy and the items rejected in the response are returned in step.RejectedError to be skipped
+func NewHttpDocWriter[R, K any](endpoint string, opt HttpOption) step.Writer[R, K] {
+	if opt.Method == "" {
+		opt.Method = http.MethodPost
+	}
+	if opt.Format == "" {
+		opt.Format = JsonArrayBody
+	}
+
+	return &httpDocWriter[R, K]{
+		endpoint: endpoint,
+		opt:      opt,
+	}
+}
+
+func (hdw *httpDocWriter[R, K]) Write(items []R, pCtx parallel.Partition) (int64, error) {
+	return hdw.WriteContext(context.Background(), items, pCtx)
+}
+
+// WriteContext stops the request and the backoff of the retries when ctx is cancelled
+func (hdw *httpDocWriter[R, K]) WriteContext(ctx context.Context, items []R, pCtx parallel.Partition) (int64, error) {
+	op := er.GetOperator()
+
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	body, err := hdw.encode(items)
+	if err != nil {
+		return 0, er.WrapOpAndKind(err, op, er.KindInvalidItem)
+	}
+
+	res, err := rest.Do(ctx, hdw.opt.Client, hdw.opt.Policy, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, hdw.opt.Method, hdw.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range hdw.opt.Header {
+			req.Header[k] = v
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if hdw.opt.Format == NdjsonBody {
+			req.Header.Set("Content-Type", "application/x-ndjson")
+		}
+		if hdw.opt.Gzip {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		return req, nil
+	})
+	if err != nil {
+		log.Err(err).Msgf("[%s] request: %s", pCtx.PartitionName(), hdw.endpoint)
+
+		var statusErr *rest.StatusError
+		if errors.As(err, &statusErr) {
+			return 0, er.WrapOpAndKind(err, op, er.HTTPStatusToKind(statusErr.StatusCode))
+		}
+		return 0, er.WrapOp(err, op)
+	}
+
+	rejected, err := hdw.rejected(res.Body, len(items))
+	if err != nil {
+		return 0, er.WrapOp(err, op)
+	}
+
+	if rejected == nil {
+		return int64(len(items)), nil
+	}
+
+	log.Warn().Msgf("[%s] %d of %d items were rejected: %s", pCtx.PartitionName(), rejected.Count, len(items), rest.Field(res.Body, hdw.opt.RejectedField))
+
+	return int64(len(items) - rejected.Count), rejected
+}
+
+// Retries returns true if rest.Policy retries the requests, so the step doesn't retry them again
+func (hdw *httpDocWriter[R, K]) Retries() bool {
+	return hdw.opt.MaxRetries > 0
+}
+
+func (hdw *httpDocWriter[R, K]) encode(items []R) ([]byte, error) {
+	var body bytes.Buffer
+	var out io.Writer = &body
+
+	var gz *gzip.Writer
+	if hdw.opt.Gzip {
+		gz = gzip.NewWriter(&body)
+		out = gz
+	}
+
+	enc := json.NewEncoder(out)
+
+	switch hdw.opt.Format {
+	case JsonArrayBody:
+		if err := enc.Encode(items); err != nil {
+			return nil, err
+		}
+	case NdjsonBody:
+		for _, item := range items {
+			// Encode ends the item with a line break
+			if err := enc.Encode(item); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, errors.Errorf("unsupported body format [%s]", hdw.opt.Format)
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return body.Bytes(), nil
+}
+
+// rejected returns the items rejected in the response or nil if none. The field is the array of them or the number
+// The indexes of them are known if the elements of the array are the indexes or have IndexField
+func (hdw *httpDocWriter[R, K]) rejected(body []byte, size int) (*step.RejectedError, error) {
+	if hdw.opt.RejectedField == "" {
+		return nil, nil
+	}
+
+	raw := rest.Field(body, hdw.opt.RejectedField)
+
+	var count int
+	var indexes []int
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		count = len(list)
+		indexes = hdw.indexes(list, size)
+	} else if err := json.Unmarshal(raw, &count); err != nil {
+		return nil, errors.Wrapf(err, "rejected [%s] of the response", hdw.opt.RejectedField)
+	}
+
+	if count <= 0 {
+		return nil, nil
+	}
+	if count > size {
+		count = size
+	}
+
+	return &step.RejectedError{
+		Count:   count,
+		Indexes: indexes,
+		Err:     er.WrapOpAndKind(errors.Errorf("rejected by %s: %s", hdw.endpoint, raw), er.GetOperator(), er.KindInvalidItem),
+	}, nil
+}
+
+// indexes returns the indexes of the rejected items in the chunk or nil if any of them is unknown
+func (hdw *httpDocWriter[R, K]) indexes(list []json.RawMessage, size int) []int {
+	indexes := make([]int, 0, len(list))
+	for _, item := range list {
+		if hdw.opt.IndexField != "" {
+			item = rest.Field(item, hdw.opt.IndexField)
+		}
+
+		var index int
+		if err := json.Unmarshal(item, &index); err != nil || index < 0 || index >= size {
+			return nil
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
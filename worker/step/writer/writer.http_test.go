@@ -0,0 +1,130 @@
+package writer
+
+import (
+	"compress/gzip"
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/rest"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_HttpDocWriter(t *testing.T) {
+	pCtx := parallel.RestorePartition("pCtx0", 1, 100, 0, parallel.AutoIncrementIdType)
+	items := []fileDocMock{{Id: 1, Title: "a"}, {Id: 2, Title: "b"}, {Id: 3, Title: "c"}}
+
+	t.Run("post the chunk as json array and return the rejected items", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, `[{"id":1,"title":"a"},{"id":2,"title":"b"},{"id":3,"title":"c"}]`+"\n", string(body))
+
+			_, _ = w.Write([]byte(`{"result":{"errors":[{"index":1,"reason":"duplicated"}]}}`))
+		}))
+		defer srv.Close()
+
+		w := NewHttpDocWriter[fileDocMock, any](srv.URL, HttpOption{
+			Client:        srv.Client(),
+			Header:        http.Header{"Authorization": {"Bearer token"}},
+			RejectedField: "result.errors",
+			IndexField:    "index",
+			Policy:        rest.Policy{MaxRetries: 1, RetryWait: time.Millisecond},
+		})
+
+		affected, err := w.Write(items, pCtx)
+		assert.Equal(t, int64(2), affected)
+		assert.Equal(t, int32(2), calls)
+
+		var rejected *step.RejectedError
+		assert.ErrorAs(t, err, &rejected)
+		assert.Equal(t, 1, rejected.Count)
+		assert.Equal(t, []int{1}, rejected.Indexes)
+		assert.True(t, er.IsKind(rejected.Err, er.KindInvalidItem))
+		assert.True(t, w.(step.Retrier).Retries())
+	})
+
+	t.Run("return the number of the rejected items without the indexes", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"rejected":5}`))
+		}))
+		defer srv.Close()
+
+		w := NewHttpDocWriter[fileDocMock, any](srv.URL, HttpOption{Client: srv.Client(), RejectedField: "rejected"})
+
+		affected, err := w.Write(items, pCtx)
+		assert.Equal(t, int64(0), affected)
+
+		var rejected *step.RejectedError
+		assert.ErrorAs(t, err, &rejected)
+		assert.Equal(t, 3, rejected.Count)
+		assert.Nil(t, rejected.Indexes)
+		assert.False(t, w.(step.Retrier).Retries())
+	})
+
+	t.Run("stop the backoff when the context is cancelled", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer srv.Close()
+
+		w := NewHttpDocWriter[fileDocMock, any](srv.URL, HttpOption{Client: srv.Client(), Policy: rest.Policy{MaxRetries: 3}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := w.(step.ContextWriter[fileDocMock]).WriteContext(ctx, items, pCtx)
+		assert.True(t, er.Is(err, context.Canceled))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("compress ndjson with gzip", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+			assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+			gz, err := gzip.NewReader(r.Body)
+			assert.NoError(t, err)
+			body, _ := io.ReadAll(gz)
+			assert.Equal(t, "{\"id\":1,\"title\":\"a\"}\n{\"id\":2,\"title\":\"b\"}\n{\"id\":3,\"title\":\"c\"}\n", string(body))
+
+			_, _ = w.Write([]byte(`{"rejected":0}`))
+		}))
+		defer srv.Close()
+
+		w := NewHttpDocWriter[fileDocMock, any](srv.URL, HttpOption{Client: srv.Client(), Format: NdjsonBody, Gzip: true, RejectedField: "rejected"})
+
+		affected, err := w.Write(items, pCtx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), affected)
+	})
+
+	t.Run("fail with the kind of the status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}))
+		defer srv.Close()
+
+		w := NewHttpDocWriter[fileDocMock, any](srv.URL, HttpOption{Client: srv.Client(), Policy: rest.Policy{MaxRetries: 3}})
+
+		_, err := w.Write(items, pCtx)
+		assert.Error(t, err)
+		assert.True(t, er.IsKind(err, er.KindInvalidItem))
+	})
+}
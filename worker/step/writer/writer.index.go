@@ -0,0 +1,157 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// IndexClient is the search index client used by the indexer writer
+// Bulk indexes docs into indexName and returns the number of documents that were accepted
+type IndexClient interface {
+	Bulk(ctx context.Context, indexName string, docs []any) (int64, error)
+}
+
+// IndexDocument can be implemented by R to set the id of the indexed document
+type IndexDocument interface {
+	DocumentId() string
+}
+
+type indexDocWriter[R, K any] struct {
+	indexName        string
+	docWriterStorage step.WriterStorage[K]
+}
+
+func NewIndexDocWriter[R, K any](indexName string, client step.WriterStorage[K]) step.Writer[R, K] {
+	return &indexDocWriter[R, K]{
+		indexName:        indexName,
+		docWriterStorage: client,
+	}
+}
+
+func (idw *indexDocWriter[R, K]) Write(items []R, pCtx parallel.Partition) (int64, error) {
+	return idw.WriteContext(context.Background(), items, pCtx)
+}
+
+// WriteContext bulk indexes items until ctx is cancelled. see step.ContextWriter
+func (idw *indexDocWriter[R, K]) WriteContext(ctx context.Context, items []R, pCtx parallel.Partition) (int64, error) {
+	op := er.GetOperator()
+
+	client, ok := any(idw.docWriterStorage.GetClient()).(IndexClient)
+	if !ok {
+		return 0, er.WrapOp(errors.New("cannot convert docWriterStorage client"), op)
+	}
+
+	docs := make([]any, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, item)
+	}
+
+	// the failed bulk is retried by step.FaultTolerance
+	rowsAffCount, err := client.Bulk(ctx, idw.indexName, docs)
+	if err != nil {
+		return 0, er.WrapOp(err, op)
+	}
+
+	return rowsAffCount, nil
+}
+
+// HttpIndexClient is IndexClient that sends documents to the `_bulk` API of elasticsearch compatible index
+type HttpIndexClient struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewHttpIndexClient(endpoint string, client *http.Client) *HttpIndexClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HttpIndexClient{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   client,
+	}
+}
+
+type bulkAction struct {
+	Index bulkActionMeta `json:"index"`
+}
+
+type bulkActionMeta struct {
+	Id string `json:"_id,omitempty"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+	} `json:"items"`
+}
+
+func (hic *HttpIndexClient) Bulk(ctx context.Context, indexName string, docs []any) (int64, error) {
+	op := er.GetOperator()
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+
+	for _, doc := range docs {
+		var action bulkAction
+		if d, ok := doc.(IndexDocument); ok {
+			action.Index.Id = d.DocumentId()
+		}
+
+		if err := enc.Encode(action); err != nil {
+			return 0, er.WrapOp(err, op)
+		}
+
+		if err := enc.Encode(doc); err != nil {
+			return 0, er.WrapOp(err, op)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s/_bulk", hic.endpoint, indexName), &body)
+	if err != nil {
+		return 0, er.WrapOp(err, op)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := hic.client.Do(req)
+	if err != nil {
+		return 0, er.WrapOp(err, op)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, er.WrapOp(errors.Errorf("bulk index [%s] failed with status %d: %s", indexName, resp.StatusCode, msg), op)
+	}
+
+	var res bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, er.WrapOp(err, op)
+	}
+
+	var indexed int64
+	for _, item := range res.Items {
+		for _, r := range item {
+			if r.Status < http.StatusBadRequest {
+				indexed++
+			}
+		}
+	}
+
+	if res.Errors {
+		log.Warn().Msgf("[%s] %d of %d documents were rejected", indexName, int64(len(docs))-indexed, len(docs))
+	}
+
+	return indexed, nil
+}
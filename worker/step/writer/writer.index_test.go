@@ -0,0 +1,121 @@
+package writer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_IndexDocWriter(t *testing.T) {
+	t.Run("write documents into the index named destIndexName", func(t *testing.T) {
+		var path string
+		var ids []string
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+
+			var items []string
+			sc := bufio.NewScanner(r.Body)
+			for i := 0; sc.Scan(); i++ {
+				if i%2 == 1 {
+					continue
+				}
+
+				var action bulkAction
+				assert.NoError(t, json.Unmarshal(sc.Bytes(), &action))
+				ids = append(ids, action.Index.Id)
+
+				status := http.StatusCreated
+				if action.Index.Id == "2" {
+					status = http.StatusBadRequest
+				}
+				items = append(items, fmt.Sprintf(`{"index":{"status":%d}}`, status))
+			}
+
+			fmt.Fprintf(w, `{"errors":true,"items":[%s]}`, strings.Join(items, ","))
+		}))
+		defer srv.Close()
+
+		w := NewIndexDocWriter[indexDocMock, HttpIndexClient]("faqs_kr", &indexStorageMock{
+			client: NewHttpIndexClient(srv.URL, srv.Client()),
+		})
+
+		affected, err := w.Write([]indexDocMock{{Id: "1"}, {Id: "2"}, {Id: "3"}}, parallel.EmptyPartition)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/faqs_kr/_bulk", path)
+		assert.Equal(t, []string{"1", "2", "3"}, ids)
+		assert.Equal(t, int64(2), affected)
+	})
+
+	t.Run("fail when index responds with error status", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		w := NewIndexDocWriter[indexDocMock, HttpIndexClient]("faqs_kr", &indexStorageMock{
+			client: NewHttpIndexClient(srv.URL, srv.Client()),
+		})
+
+		_, err := w.Write([]indexDocMock{{Id: "1"}}, parallel.EmptyPartition)
+
+		assert.Error(t, err)
+		// the retries are left to step.FaultTolerance
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("stop the bulk when the context is cancelled", func(t *testing.T) {
+		var calls int32
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+		}))
+		defer srv.Close()
+		defer close(release)
+
+		w := NewIndexDocWriter[indexDocMock, HttpIndexClient]("faqs_kr", &indexStorageMock{
+			client: NewHttpIndexClient(srv.URL, srv.Client()),
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := w.(step.ContextWriter[indexDocMock]).WriteContext(ctx, []indexDocMock{{Id: "1"}}, parallel.EmptyPartition)
+
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		// not retried after the cancel
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+type indexDocMock struct {
+	Id string `json:"id"`
+}
+
+func (d indexDocMock) DocumentId() string {
+	return d.Id
+}
+
+type indexStorageMock struct {
+	client *HttpIndexClient
+}
+
+func (m *indexStorageMock) GetClient() *HttpIndexClient {
+	return m.client
+}
@@ -0,0 +1,211 @@
+package writer
+
+import (
+	"fmt"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/dialect"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/mapper"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"regexp"
+	"strings"
+)
+
+// defaultMaxPlaceholders is the placeholder limit of the drivers not known by dialect (SQLite before 3.32)
+const defaultMaxPlaceholders = 999
+
+var (
+	valuesPattern = regexp.MustCompile(`(?i)\)\s*VALUES\s*\(`)
+	namePattern   = regexp.MustCompile(`(^|[^:]):([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// bulkInsert is the named insert query compiled to the multi-row statements of positional bind variables
+type bulkInsert struct {
+	prefix    string // "INSERT INTO t (a, b) VALUES "
+	row       string // "(?, ?)"
+	suffix    string // " ON CONFLICT ..."
+	names     []string
+	copyQuery string // "COPY t (a, b) FROM STDIN". empty if COPY is not used
+	maxRows   int
+	bindType  int
+	mapped    *mapper.Fields
+	fields    []mapper.Field // fields of names in the item type
+}
+
+func compileBulkInsert(query, copyTable string, db *sqlx.DB) (*bulkInsert, error) {
+	loc := valuesPattern.FindStringIndex(query)
+	if loc == nil {
+		return nil, errors.Errorf("bulk insert needs VALUES (...) [%s]", query)
+	}
+
+	open := loc[1] - 1
+	closing := matchingBracket(query[open:])
+	if closing < 0 {
+		return nil, errors.Errorf("unclosed VALUES (...) [%s]", query)
+	}
+	closing += open + 1
+
+	bi := &bulkInsert{
+		prefix:   query[:open],
+		suffix:   query[closing:],
+		bindType: sqlx.BindType(db.DriverName()),
+	}
+
+	if namePattern.MatchString(bi.suffix) {
+		return nil, errors.Errorf("bulk insert cannot bind parameters after VALUES (...) [%s]", query)
+	}
+
+	// the named parameters of the row are replaced with "?"
+	bi.row = namePattern.ReplaceAllStringFunc(query[open:closing], func(m string) string {
+		sub := namePattern.FindStringSubmatch(m)
+		bi.names = append(bi.names, sub[2])
+		return sub[1] + "?"
+	})
+
+	if len(bi.names) == 0 {
+		return nil, errors.Errorf("bulk insert needs named parameters [%s]", query)
+	}
+
+	maxPlaceholders := defaultMaxPlaceholders
+	d, err := dialect.FromDriverName(db.DriverName())
+	if err == nil {
+		maxPlaceholders = d.MaxPlaceholders()
+	}
+
+	bi.maxRows = maxPlaceholders / len(bi.names)
+	if bi.maxRows < 1 {
+		return nil, errors.Errorf("%d columns exceed the placeholder limit %d", len(bi.names), maxPlaceholders)
+	}
+
+	// only lib/pq supports COPY FROM STDIN through database/sql
+	if copyTable != "" && db.DriverName() == "postgres" && bi.suffix == "" && isPlainRow(bi.row, len(bi.names)) {
+		columns := make([]string, 0, len(bi.names))
+		for _, n := range bi.names {
+			columns = append(columns, dialect.Postgres.Quote(n))
+		}
+		bi.copyQuery = fmt.Sprintf("COPY %s (%s) FROM STDIN", dialect.Postgres.Quote(copyTable), strings.Join(columns, ", "))
+	}
+
+	return bi, nil
+}
+
+// statement returns the statement inserting n rows
+func (bi *bulkInsert) statement(n int) string {
+	rows := strings.Repeat(bi.row+", ", n-1) + bi.row
+	return sqlx.Rebind(bi.bindType, bi.prefix+rows+bi.suffix)
+}
+
+// bind resolves the fields of names in the item type once
+func (bi *bulkInsert) bind(mapped *mapper.Fields) error {
+	if bi.mapped == mapped {
+		return nil
+	}
+
+	fields, err := mapped.Select(bi.names)
+	if err != nil {
+		return err
+	}
+
+	bi.mapped, bi.fields = mapped, fields
+	return nil
+}
+
+// writeBulk inserts items by COPY or by multi-row statements of up to maxRows rows
+func writeBulk[R any](tx *sqlx.Tx, bi *bulkInsert, items []R) (int64, error) {
+	if err := bi.bind(mapper.For[R]()); err != nil {
+		return 0, err
+	}
+
+	if bi.copyQuery != "" {
+		return copyIn(tx, bi, items)
+	}
+
+	var rowsAffCount int64
+	var stmt string
+	args := make([]any, 0, len(bi.names)*minInt(len(items), bi.maxRows))
+
+	for start := 0; start < len(items); start += bi.maxRows {
+		end := minInt(start+bi.maxRows, len(items))
+
+		args = args[:0]
+		for _, item := range items[start:end] {
+			args = bi.mapped.Values(item, bi.fields, args)
+		}
+
+		// the statement of maxRows rows is reused for the full batches
+		if end-start != bi.maxRows || stmt == "" {
+			stmt = bi.statement(end - start)
+		}
+
+		res, err := tx.Exec(stmt, args...)
+		if err != nil {
+			return rowsAffCount, err
+		}
+
+		rowsAff, err := res.RowsAffected()
+		if err != nil {
+			return rowsAffCount, err
+		}
+		rowsAffCount += rowsAff
+	}
+
+	return rowsAffCount, nil
+}
+
+// copyIn loads items by the COPY FROM STDIN protocol of lib/pq. Every Exec buffers a row and the Exec without arguments flushes them
+func copyIn[R any](tx *sqlx.Tx, bi *bulkInsert, items []R) (int64, error) {
+	stmt, err := tx.Prepare(bi.copyQuery)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	args := make([]any, 0, len(bi.names))
+	for _, item := range items {
+		args = bi.mapped.Values(item, bi.fields, args[:0])
+
+		if _, err := stmt.Exec(args...); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := stmt.Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	// the drivers not reporting the count of COPY
+	if rowsAff, err := res.RowsAffected(); err == nil && rowsAff > 0 {
+		return rowsAff, nil
+	}
+
+	return int64(len(items)), nil
+}
+
+// matchingBracket returns the index of the bracket closing s[0] or -1
+func matchingBracket(s string) int {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isPlainRow reports whether row is "(?, ?, ...)" without expressions
+func isPlainRow(row string, n int) bool {
+	return strings.ReplaceAll(row, " ", "") == "("+strings.TrimSuffix(strings.Repeat("?,", n), ",")+")"
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,61 @@
+package writer
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var mysqlErrorPattern = regexp.MustCompile(`^Error (\d+)`)
+
+// mysql error numbers of the rows rejected by the constraints or the values
+var (
+	mysqlDuplicate = map[int]bool{1062: true, 1586: true}
+	mysqlInvalid   = map[int]bool{
+		1048: true, 1216: true, 1217: true, 1264: true, 1292: true, 1364: true,
+		1366: true, 1406: true, 1451: true, 1452: true, 3819: true, 4025: true,
+	}
+)
+
+// writeErrorKind returns the kind of the error of the statement writing the items
+// The duplicate keys are er.KindConflict and the other constraint or data errors are er.KindInvalidItem,
+// so FaultTolerance.SkipKinds can skip the rejected rows but not the connection errors (er.KindUndefined)
+func writeErrorKind(err error) er.Kind {
+	// pgx and lib/pq
+	var e interface{ SQLState() string }
+	if errors.As(err, &e) {
+		switch state := e.SQLState(); {
+		case state == "23505":
+			return er.KindConflict
+		case strings.HasPrefix(state, "23"), strings.HasPrefix(state, "22"):
+			return er.KindInvalidItem
+		}
+		return er.KindUndefined
+	}
+
+	msg := err.Error()
+
+	// go-sql-driver/mysql (example. "Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'")
+	if m := mysqlErrorPattern.FindStringSubmatch(msg); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		switch {
+		case mysqlDuplicate[number]:
+			return er.KindConflict
+		case mysqlInvalid[number]:
+			return er.KindInvalidItem
+		}
+		return er.KindUndefined
+	}
+
+	// sqlite (example. "UNIQUE constraint failed: faqs.id")
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"), strings.Contains(msg, "PRIMARY KEY constraint failed"):
+		return er.KindConflict
+	case strings.Contains(msg, "constraint failed"), strings.Contains(msg, "datatype mismatch"):
+		return er.KindInvalidItem
+	}
+
+	return er.KindUndefined
+}
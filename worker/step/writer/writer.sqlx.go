@@ -2,61 +2,184 @@ package writer
 
 import (
 	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
-	"github.com/Hoyaspark/go-partitioning-batch/util"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/mapper"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
-	"github.com/fatih/structs"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
-	"time"
+	"github.com/rs/zerolog/log"
 )
 
+// sqlxDocWriter writes the chunks in a transaction committed by the step. see step.Committer
 type sqlxDocWriter[R, K any] struct {
 	queryString      string // need to set "INSERT INTO ~~~"
 	docWriterStorage step.WriterStorage[K]
+	tx               *sqlx.Tx
+
+	deleteQuery string // deletes the range of the partition before the first chunk is written (example. dialect.Dialect.DeleteRange)
+	deleted     bool   // the range is deleted in tx
+	committed   bool   // the delete is committed
+
+	bulk      bool
+	copyTable string
+	insert    *bulkInsert // compiled from queryString at the first Write of bulk
+}
+
+// SqlxWriterOption is the option of NewSqlxDocWriterWithOption
+type SqlxWriterOption struct {
+	// DeleteQuery deletes the range of the partition in the transaction of the first chunk
+	// It binds :from and :to to Min() and Max() of the partition, so rerunning a partition doesn't duplicate the rows
+	DeleteQuery string
+	// Bulk inserts a chunk with multi-row statements split under the placeholder limit of the driver instead of sqlx batch insert
+	Bulk bool
+	// CopyTable loads the chunk of Bulk by COPY FROM STDIN into the table with the lib/pq driver ("postgres")
+	// The VALUES of the query must be the named parameters of the columns (example. dialect.Dialect.Insert)
+	CopyTable string
 }
 
 func NewSqlxDocWriter[R, K any](queryString string, db step.WriterStorage[K]) step.Writer[R, K] {
+	return NewSqlxDocWriterWithOption[R, K](queryString, db, SqlxWriterOption{})
+}
+
+// NewDeleteInsertSqlxDocWriter returns the sqlx Writer that deletes the range of the partition with deleteQuery. see SqlxWriterOption.DeleteQuery
+func NewDeleteInsertSqlxDocWriter[R, K any](deleteQuery, queryString string, db step.WriterStorage[K]) step.Writer[R, K] {
+	return NewSqlxDocWriterWithOption[R, K](queryString, db, SqlxWriterOption{DeleteQuery: deleteQuery})
+}
+
+// NewBulkSqlxDocWriter returns the sqlx Writer that inserts the chunks in bulk. see SqlxWriterOption.Bulk
+func NewBulkSqlxDocWriter[R, K any](queryString, copyTable string, db step.WriterStorage[K]) step.Writer[R, K] {
+	return NewSqlxDocWriterWithOption[R, K](queryString, db, SqlxWriterOption{Bulk: true, CopyTable: copyTable})
+}
+
+func NewSqlxDocWriterWithOption[R, K any](queryString string, db step.WriterStorage[K], opt SqlxWriterOption) step.Writer[R, K] {
 	return &sqlxDocWriter[R, K]{
 		queryString:      queryString,
 		docWriterStorage: db,
+		deleteQuery:      opt.DeleteQuery,
+		bulk:             opt.Bulk,
+		copyTable:        opt.CopyTable,
 	}
 }
 
 func (sdw *sqlxDocWriter[R, K]) Write(items []R, pCtx parallel.Partition) (int64, error) {
-	var rowsAffCount int64
-
 	op := er.GetOperator()
 
-	db, ok := any(sdw.docWriterStorage.GetClient()).(*sqlx.DB)
-	if !ok {
-		return 0, er.WrapOp(errors.New("cannot convert docWriterStorage client"), op)
-	}
-
-	err := util.RetryFunc(2, 100*time.Millisecond, func() (err error) {
-		var buf []map[string]interface{}
+	if sdw.tx == nil {
+		db, ok := any(sdw.docWriterStorage.GetClient()).(*sqlx.DB)
+		if !ok {
+			return 0, er.WrapOp(errors.New("cannot convert docWriterStorage client"), op)
+		}
 
-		for _, item := range items {
-			buf = append(buf, structs.Map(item))
+		if sdw.bulk && sdw.insert == nil {
+			insert, err := compileBulkInsert(sdw.queryString, sdw.copyTable, db)
+			if err != nil {
+				return 0, er.WrapOp(err, op)
+			}
+			sdw.insert = insert
 		}
 
-		rowsRes, err := db.NamedExec(sdw.queryString, buf)
+		tx, err := db.Beginx()
 		if err != nil {
-			return err
+			return 0, er.WrapOp(err, op)
 		}
+		sdw.tx = tx
+	}
+
+	if sdw.deleteQuery != "" && !sdw.committed && !sdw.deleted {
+		if err := sdw.deleteRange(pCtx); err != nil {
+			return 0, er.WrapOp(err, op)
+		}
+		sdw.deleted = true
+	}
 
-		rowsAff, err := rowsRes.RowsAffected()
+	if sdw.insert != nil {
+		rowsAff, err := writeBulk(sdw.tx, sdw.insert, items)
 		if err != nil {
-			return err
+			return 0, er.WrapOpAndKind(err, op, writeErrorKind(err))
 		}
+		return rowsAff, nil
+	}
+
+	mapped := mapper.For[R]()
+	buf := make([]map[string]interface{}, 0, len(items))
+
+	for _, item := range items {
+		buf = append(buf, mapped.Map(item))
+	}
 
-		rowsAffCount += rowsAff
+	rowsRes, err := sdw.tx.NamedExec(sdw.queryString, buf)
+	if err != nil {
+		return 0, er.WrapOpAndKind(err, op, writeErrorKind(err))
+	}
 
-		return
-	})
+	rowsAff, err := rowsRes.RowsAffected()
 	if err != nil {
 		return 0, er.WrapOp(err, op)
 	}
 
-	return rowsAffCount, nil
+	return rowsAff, nil
+}
+
+func (sdw *sqlxDocWriter[R, K]) Commit() error {
+	op := er.GetOperator()
+
+	if sdw.tx == nil {
+		return nil
+	}
+
+	tx := sdw.tx
+	sdw.tx = nil
+
+	if err := tx.Commit(); err != nil {
+		sdw.deleted = false
+		return er.WrapOp(err, op)
+	}
+
+	sdw.committed = sdw.committed || sdw.deleted
+
+	return nil
+}
+
+func (sdw *sqlxDocWriter[R, K]) Rollback() error {
+	op := er.GetOperator()
+
+	if sdw.tx == nil {
+		return nil
+	}
+
+	tx := sdw.tx
+	sdw.tx = nil
+	sdw.deleted = false
+
+	if err := tx.Rollback(); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+// CanDeleteRange returns true if the min and max of the partition type are the range of the key column deleted by SqlxWriterOption.DeleteQuery
+func CanDeleteRange(partitionType int64) bool {
+	switch partitionType {
+	case parallel.AutoIncrementIdType, parallel.KeysetType:
+		return true
+	}
+	return false
+}
+
+func (sdw *sqlxDocWriter[R, K]) deleteRange(pCtx parallel.Partition) error {
+	if !CanDeleteRange(pCtx.Type()) {
+		return errors.Errorf("cannot delete the range of %s partition", parallel.TypeName(pCtx.Type()))
+	}
+
+	res, err := sdw.tx.NamedExec(sdw.deleteQuery, map[string]any{"from": pCtx.Min(), "to": pCtx.Max()})
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		log.Info().Msgf("[%s] deleted %d rows of [min:%d] [max:%d]", pCtx.PartitionName(), n, pCtx.Min(), pCtx.Max())
+	}
+
+	return nil
 }
@@ -0,0 +1,439 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step/processor"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type faqMock struct {
+	Id    int64  `db:"id"`
+	Title string `db:"title"`
+}
+
+func Test_BulkSqlxDocWriter(t *testing.T) {
+	items := make([]faqMock, 0, 5)
+	for i := int64(1); i <= 5; i++ {
+		items = append(items, faqMock{Id: i, Title: "title"})
+	}
+
+	t.Run("split a chunk under the placeholder limit", func(t *testing.T) {
+		db, conn := newDriverMock("mysql")
+
+		bi, err := compileBulkInsert("INSERT INTO faqs (id, title) VALUES (:id, :title)", "", db)
+		assert.NoError(t, err)
+		bi.maxRows = 2
+
+		tx, err := db.Beginx()
+		assert.NoError(t, err)
+
+		affected, err := writeBulk(tx, bi, items)
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Commit())
+
+		assert.Equal(t, int64(5), affected)
+		assert.Equal(t, []string{
+			"INSERT INTO faqs (id, title) VALUES (?, ?), (?, ?)",
+			"INSERT INTO faqs (id, title) VALUES (?, ?), (?, ?)",
+			"INSERT INTO faqs (id, title) VALUES (?, ?)",
+		}, conn.queries)
+		assert.Equal(t, []driver.Value{int64(5), "title"}, conn.args[2])
+	})
+
+	t.Run("keep the conflict clause and rebind for postgres", func(t *testing.T) {
+		db, conn := newDriverMock("postgres")
+
+		w := NewBulkSqlxDocWriter[faqMock, sqlx.DB](`INSERT INTO "faqs" ("id", "title") VALUES (:id, :title) ON CONFLICT DO NOTHING`, "faqs", &sqlStorageMock{db})
+		affected, err := w.Write(items[:2], parallel.EmptyPartition)
+		assert.NoError(t, err)
+		assert.NoError(t, w.(*sqlxDocWriter[faqMock, sqlx.DB]).Commit())
+
+		assert.Equal(t, int64(2), affected)
+		assert.Equal(t, []string{`INSERT INTO "faqs" ("id", "title") VALUES ($1, $2), ($3, $4) ON CONFLICT DO NOTHING`}, conn.queries)
+		assert.Equal(t, 1, conn.commits)
+	})
+
+	t.Run("load by copy with lib/pq", func(t *testing.T) {
+		db, conn := newDriverMock("postgres")
+
+		w := NewBulkSqlxDocWriter[*faqMock, sqlx.DB](`INSERT INTO "faqs" ("id", "title") VALUES (:id, :title)`, "faqs", &sqlStorageMock{db})
+		affected, err := w.Write([]*faqMock{&items[0], &items[1], &items[2]}, parallel.EmptyPartition)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(3), affected)
+		assert.Equal(t, `COPY "faqs" ("id", "title") FROM STDIN`, conn.queries[0])
+		// 3 rows and the flush
+		assert.Len(t, conn.args, 4)
+		assert.Empty(t, conn.args[3])
+	})
+
+	t.Run("fail with a field missing in the item", func(t *testing.T) {
+		db, _ := newDriverMock("mysql")
+
+		w := NewBulkSqlxDocWriter[faqMock, sqlx.DB]("INSERT INTO faqs (id, body) VALUES (:id, :body)", "", &sqlStorageMock{db})
+		_, err := w.Write(items, parallel.EmptyPartition)
+		assert.Error(t, err)
+	})
+
+	t.Run("fail with parameters after values", func(t *testing.T) {
+		db, _ := newDriverMock("mysql")
+
+		_, err := compileBulkInsert("INSERT INTO faqs (id) VALUES (:id) ON DUPLICATE KEY UPDATE title = :title", "", db)
+		assert.Error(t, err)
+	})
+}
+
+func Test_SqlxDocWriter(t *testing.T) {
+	type audited struct {
+		UpdatedBy string `db:"updated_by,omitempty"`
+	}
+	type faqDoc struct {
+		faqMock
+		audited
+		Memo string `db:"-"`
+	}
+
+	t.Run("bind the fields by db tags with embedded structs", func(t *testing.T) {
+		db, conn := newDriverMock("mysql")
+
+		w := NewSqlxDocWriter[faqDoc, sqlx.DB]("INSERT INTO faqs (id, title, updated_by) VALUES (:id, :title, :updated_by)", &sqlStorageMock{db})
+		_, err := w.Write([]faqDoc{
+			{faqMock: faqMock{Id: 1, Title: "a"}, audited: audited{UpdatedBy: "batch"}},
+			{faqMock: faqMock{Id: 2, Title: "b"}},
+		}, parallel.EmptyPartition)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"INSERT INTO faqs (id, title, updated_by) VALUES (?, ?, ?),(?, ?, ?)"}, conn.queries)
+		assert.Equal(t, []driver.Value{int64(1), "a", "batch", int64(2), "b", nil}, conn.args[0])
+	})
+}
+
+func Test_DeleteInsertSqlxDocWriter(t *testing.T) {
+	const (
+		deleteQuery = "DELETE FROM faqs WHERE id >= :from AND id <= :to"
+		insertQuery = "INSERT INTO faqs (id, title) VALUES (:id, :title)"
+		deleted     = "DELETE FROM faqs WHERE id >= ? AND id <= ?"
+		inserted    = "INSERT INTO faqs (id, title) VALUES (?, ?)"
+	)
+	pCtx := parallel.RestorePartition("pCtx0", 1, 4, 0, parallel.AutoIncrementIdType)
+
+	newWriter := func() (*sqlxDocWriter[faqMock, sqlx.DB], *connMock) {
+		db, conn := newDriverMock("mysql")
+		w := NewDeleteInsertSqlxDocWriter[faqMock, sqlx.DB](deleteQuery, insertQuery, &sqlStorageMock{db})
+		return w.(*sqlxDocWriter[faqMock, sqlx.DB]), conn
+	}
+
+	t.Run("delete the range of the partition once in the transaction of the first chunk", func(t *testing.T) {
+		w, conn := newWriter()
+
+		_, err := w.Write([]faqMock{{Id: 1}}, pCtx)
+		assert.NoError(t, err)
+		_, err = w.Write([]faqMock{{Id: 2}}, pCtx)
+		assert.NoError(t, err)
+		assert.True(t, w.deleted)
+		assert.False(t, w.committed)
+
+		assert.NoError(t, w.Commit())
+		assert.True(t, w.committed)
+
+		_, err = w.Write([]faqMock{{Id: 3}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Commit())
+
+		assert.Equal(t, []string{deleted, inserted, inserted, inserted}, conn.queries)
+		assert.Equal(t, []driver.Value{int64(1), int64(4)}, conn.args[0])
+		assert.Equal(t, 2, conn.commits)
+	})
+
+	t.Run("delete again after the rollback of the first transaction", func(t *testing.T) {
+		w, conn := newWriter()
+
+		_, err := w.Write([]faqMock{{Id: 1}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Rollback())
+		assert.False(t, w.deleted)
+		assert.False(t, w.committed)
+
+		// the step writes the rolled back chunk again
+		_, err = w.Write([]faqMock{{Id: 1}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Commit())
+
+		assert.Equal(t, []string{deleted, inserted, deleted, inserted}, conn.queries)
+		assert.Equal(t, 1, conn.rollbacks)
+		assert.True(t, w.committed)
+	})
+
+	t.Run("delete again when the commit fails", func(t *testing.T) {
+		w, conn := newWriter()
+		conn.commitErr = errors.New("connection reset by peer")
+
+		_, err := w.Write([]faqMock{{Id: 1}}, pCtx)
+		assert.NoError(t, err)
+		assert.Error(t, w.Commit())
+		assert.False(t, w.deleted)
+		assert.False(t, w.committed)
+
+		_, err = w.Write([]faqMock{{Id: 1}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Commit())
+
+		assert.Equal(t, []string{deleted, inserted, deleted, inserted}, conn.queries)
+		assert.True(t, w.committed)
+	})
+
+	t.Run("not delete the committed rows after a rollback", func(t *testing.T) {
+		w, conn := newWriter()
+
+		_, err := w.Write([]faqMock{{Id: 1}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Commit())
+
+		_, err = w.Write([]faqMock{{Id: 2}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Rollback())
+
+		_, err = w.Write([]faqMock{{Id: 2}}, pCtx)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Commit())
+
+		assert.Equal(t, []string{deleted, inserted, inserted, inserted}, conn.queries)
+	})
+
+	t.Run("replay the uncommitted chunks after the delete on retry", func(t *testing.T) {
+		db, conn := newDriverMock("mysql")
+		failed := false
+		conn.fail = func(args []driver.Value) error {
+			if !failed && args[0] == int64(3) {
+				failed = true
+				return errors.New("connection reset by peer")
+			}
+			return nil
+		}
+
+		s := step.NewStepWithOption[faqMock, faqMock, sqlx.DB, step.EmptyDocProcessorParamType](
+			step.NewOption(step.PagingRead, 10, 2).WithCommitInterval(2).WithFaultTolerance(step.FaultTolerance{RetryLimit: 1}),
+			&sliceReaderMock{items: []faqMock{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}}},
+			step.EmptyDocProcessorParam,
+			processor.NewProcessor[faqMock, faqMock, step.EmptyDocProcessorParamType](),
+			NewDeleteInsertSqlxDocWriter[faqMock, sqlx.DB](deleteQuery, insertQuery, &sqlStorageMock{db}))
+
+		assert.NoError(t, s.Proceed(context.Background(), pCtx, monitoring.NewMonitoring(1)))
+
+		// the failed insert is not recorded
+		insertInChunk := "INSERT INTO faqs (id, title) VALUES (?, ?),(?, ?)"
+		assert.Equal(t, []string{deleted, insertInChunk, insertInChunk, deleted, insertInChunk, insertInChunk}, conn.queries)
+		assert.Equal(t, [][]driver.Value{
+			{int64(1), int64(4)}, {int64(1), "", int64(2), ""},
+			{int64(1), int64(4)}, {int64(1), "", int64(2), ""}, {int64(3), "", int64(4), ""},
+		}, conn.args)
+		assert.Equal(t, 1, conn.rollbacks)
+		assert.Equal(t, 1, conn.commits)
+	})
+
+	t.Run("fail for the partition without the key range", func(t *testing.T) {
+		w, conn := newWriter()
+
+		_, err := w.Write([]faqMock{{Id: 1}}, parallel.RestorePartition("pCtx0", 0, 4, 0, parallel.HashType))
+		assert.Error(t, err)
+		assert.False(t, w.deleted)
+		assert.Empty(t, conn.args)
+
+		assert.True(t, CanDeleteRange(parallel.KeysetType))
+		assert.False(t, CanDeleteRange(parallel.TimeRangeType))
+	})
+}
+
+type sqlStorageMock struct {
+	db *sqlx.DB
+}
+
+func (m *sqlStorageMock) GetClient() *sqlx.DB {
+	return m.db
+}
+
+// connMock is the database/sql driver recording the statements executed
+type connMock struct {
+	mu        sync.Mutex
+	queries   []string
+	args      [][]driver.Value
+	commits   int
+	rollbacks int
+	fail      func(args []driver.Value) error // fails the statement executed with args
+	commitErr error                           // fails the next commit
+}
+
+func newDriverMock(driverName string) (*sqlx.DB, *connMock) {
+	conn := &connMock{}
+	return sqlx.NewDb(sql.OpenDB(conn), driverName), conn
+}
+
+func (c *connMock) Connect(context.Context) (driver.Conn, error) { return c, nil }
+func (c *connMock) Driver() driver.Driver                        { return nil }
+
+func (c *connMock) Prepare(query string) (driver.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queries = append(c.queries, query)
+	return &stmtMock{conn: c, copy: strings.HasPrefix(query, "COPY")}, nil
+}
+
+func (c *connMock) Close() error              { return nil }
+func (c *connMock) Begin() (driver.Tx, error) { return c, nil }
+
+func (c *connMock) Commit() error {
+	if err := c.commitErr; err != nil {
+		c.commitErr = nil
+		return err
+	}
+	c.commits++
+	return nil
+}
+
+func (c *connMock) Rollback() error {
+	c.rollbacks++
+	return nil
+}
+
+type stmtMock struct {
+	conn *connMock
+	copy bool
+}
+
+func (s *stmtMock) Close() error  { return nil }
+func (s *stmtMock) NumInput() int { return -1 }
+
+func (s *stmtMock) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+
+	if s.conn.fail != nil {
+		if err := s.conn.fail(args); err != nil {
+			return nil, err
+		}
+	}
+
+	s.conn.args = append(s.conn.args, args)
+	if s.copy {
+		// lib/pq reports no count for COPY
+		return driver.RowsAffected(0), nil
+	}
+	return driver.RowsAffected(len(args) / 2), nil
+}
+
+func (s *stmtMock) Query([]driver.Value) (driver.Rows, error) {
+	return nil, io.EOF
+}
+
+func (f faqMock) ToModel(*step.EmptyDocProcessorParamType) (*faqMock, error) {
+	return &f, nil
+}
+
+// sliceReaderMock reads items once
+type sliceReaderMock struct {
+	items []faqMock
+}
+
+func (r *sliceReaderMock) New() step.Reader[faqMock, faqMock, step.EmptyDocProcessorParamType] {
+	return r
+}
+
+func (r *sliceReaderMock) Read(context.Context, parallel.Partition) (*faqMock, bool, error) {
+	if len(r.items) == 0 {
+		return nil, true, nil
+	}
+	item := r.items[0]
+	r.items = r.items[1:]
+	return &item, false, nil
+}
+
+type deadLetterMock struct {
+	items []any
+}
+
+func (d *deadLetterMock) Write(_ string, item any, _ error) error {
+	d.items = append(d.items, item)
+	return nil
+}
+
+// pqErrorMock is the error of the postgres drivers with SQLSTATE
+type pqErrorMock struct {
+	state string
+}
+
+func (e pqErrorMock) Error() string    { return "pq: " + e.state }
+func (e pqErrorMock) SQLState() string { return e.state }
+
+func Test_SqlxDocWriterFault(t *testing.T) {
+	items := []faqMock{{Id: 1}, {Id: 2}, {Id: 3}, {Id: 4}}
+	failOn := func(id int64, err error) func([]driver.Value) error {
+		return func(args []driver.Value) error {
+			for _, arg := range args {
+				if arg == id {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	proceed := func(db *sqlx.DB, dl step.DeadLetter) error {
+		s := step.NewStepWithOption[faqMock, faqMock, sqlx.DB, step.EmptyDocProcessorParamType](
+			step.NewOption(step.PagingRead, 10, 4).WithFaultTolerance(step.FaultTolerance{
+				SkipLimit:  1,
+				SkipKinds:  []er.Kind{er.KindInvalidItem, er.KindConflict},
+				DeadLetter: dl,
+			}),
+			&sliceReaderMock{items: items},
+			step.EmptyDocProcessorParam,
+			processor.NewProcessor[faqMock, faqMock, step.EmptyDocProcessorParamType](),
+			NewSqlxDocWriter[faqMock, sqlx.DB]("INSERT INTO faqs (id, title) VALUES (:id, :title)", &sqlStorageMock{db}))
+
+		return s.Proceed(context.Background(), parallel.EmptyPartition, monitoring.NewMonitoring(1))
+	}
+
+	t.Run("skip the row rejected by the constraint", func(t *testing.T) {
+		db, conn := newDriverMock("postgres")
+		conn.fail = failOn(3, pqErrorMock{state: "23502"})
+
+		dl := &deadLetterMock{}
+		assert.NoError(t, proceed(db, dl))
+
+		assert.Equal(t, []any{faqMock{Id: 3}}, dl.items)
+		// the chunk failed and the rows are written one by one
+		assert.Equal(t, []driver.Value{int64(4), ""}, conn.args[len(conn.args)-1])
+		assert.Len(t, conn.args, 3)
+	})
+
+	t.Run("fail on the connection error", func(t *testing.T) {
+		db, conn := newDriverMock("mysql")
+		conn.fail = failOn(3, errors.New("connection reset by peer"))
+
+		dl := &deadLetterMock{}
+		assert.Error(t, proceed(db, dl))
+		assert.Empty(t, dl.items)
+	})
+
+	t.Run("classify the errors of the drivers", func(t *testing.T) {
+		assert.Equal(t, er.KindConflict, writeErrorKind(pqErrorMock{state: "23505"}))
+		assert.Equal(t, er.KindInvalidItem, writeErrorKind(pqErrorMock{state: "22001"}))
+		assert.Equal(t, er.KindUndefined, writeErrorKind(pqErrorMock{state: "08006"}))
+		assert.Equal(t, er.KindConflict, writeErrorKind(errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'")))
+		assert.Equal(t, er.KindInvalidItem, writeErrorKind(errors.New("Error 1048 (23000): Column 'title' cannot be null")))
+		assert.Equal(t, er.KindInvalidItem, writeErrorKind(errors.New("NOT NULL constraint failed: faqs.title")))
+		assert.Equal(t, er.KindUndefined, writeErrorKind(errors.New("driver: bad connection")))
+	})
+}
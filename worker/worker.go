@@ -5,14 +5,18 @@ import (
 	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/repository"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/step/processor"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/step/reader"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/step/writer"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
+	"os"
+	"os/signal"
 	"runtime"
-	"sync/atomic"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -25,6 +29,7 @@ type worker[T step.DocProcessor[R, J], R, K any, J step.ProcessorParam[J]] struc
 	stepOpt          step.Option
 	processorParam   step.ProcessorParam[J]
 	readDB           step.ReaderDB
+	source           step.Source[T] // read instead of readDB. see NewSourceWorker
 	writeDB          step.WriterStorage[K]
 	parallelTypeFunc parallel.ParallelTypeFunc
 }
@@ -48,53 +53,142 @@ func NewWorker[T step.DocProcessor[R, J], R, K any, J step.ProcessorParam[J]](
 	}
 }
 
+// NewSourceWorker is NewWorker that reads the partitions from source instead of step.ReaderDB (example. files, APIs)
+// step.ReaderType and the read query of workerOption are not used, and ParallelDB returns nil
+// writeDB can be nil with WriterWorkerOptions
+func NewSourceWorker[T step.DocProcessor[R, J], R, K any, J step.ProcessorParam[J]](
+	source step.Source[T], writeDB step.WriterStorage[K], processorParam step.ProcessorParam[J],
+	parallelTypeFunc parallel.ParallelTypeFunc, stepOpt step.Option) Worker {
+
+	return &worker[T, R, K, J]{
+		stepOpt:          stepOpt,
+		processorParam:   processorParam,
+		source:           source,
+		writeDB:          writeDB,
+		parallelTypeFunc: parallelTypeFunc,
+	}
+}
+
 func (m *worker[T, R, K, J]) ParallelDB() step.ReaderDB {
 	return m.readDB
 }
 
 func (m *worker[T, R, K, J]) GetReadQuery(sourceName string) string {
+	if m.readDB == nil {
+		return ""
+	}
 	return m.readDB.GetReadQuery(sourceName)
 }
 
-func (m *worker[T, R, K, J]) Handle(pr parallel.Parallel, workerOpt workerOption) (int64, int64, error) {
-	var totalRow, totalAffected int64
+// Handle runs HandleContext with the context that is cancelled on SIGINT or SIGTERM
+func (m *worker[T, R, K, J]) Handle(pr parallel.Parallel, workerOpt workerOption) (JobResult, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
+	return m.HandleContext(ctx, pr, workerOpt)
+}
+
+// HandleContext runs every partition and returns JobResult with the stats of each partition
+// When ctx is cancelled or a partition fails, the other partitions write the chunk in progress and stop,
+// and HandleContext returns the result so far with the first error after all of them exit
+func (m *worker[T, R, K, J]) HandleContext(ctx context.Context, pr parallel.Parallel, workerOpt workerOption) (JobResult, error) {
 	op := er.GetOperator()
 
+	now := time.Now()
+	jr := newJobResultRecorder(m.parallelTypeFunc.Name(), now)
+
+	if !workerOpt.isSet {
+		log.Error().Msg("need to set required settings [indexer,consumer,writer]")
+		return jr.end(), er.New("need to set required settings [indexer,consumer,writer]", op, er.KindFatal)
+	}
+
+	workerOpt, err := workerOpt.prepare()
+	if err != nil {
+		log.Error().Err(err).Msg("invalid worker settings")
+		return jr.end(), er.WrapOpAndKind(err, op, er.KindFatal)
+	}
+
 	m.workerOpt = workerOpt
 
-	if !m.workerOpt.isSet {
-		log.Error().Msg("need to set required settings [indexer,consumer]")
-		return 0, 0, er.New("need to set required settings [indexer,consumer]", op, er.KindFatal)
+	// only the paging reader claims the pages of parallel.SplitPartition before reading them
+	if workerOpt.workStealing && (m.source != nil || m.stepOpt.ReaderType() != step.PagingRead) {
+		log.Error().Msg("WithWorkStealing needs step.PagingRead")
+		return jr.end(), er.New("WithWorkStealing needs step.PagingRead", op, er.KindFatal)
+	}
+
+	// build a step once to fail before any partition starts
+	if _, err := m.step(); err != nil {
+		log.Error().Err(err).Msg("invalid step settings")
+		return jr.end(), er.WrapOpAndKind(err, op, er.KindFatal)
 	}
 
-	parallelCtx, err := pr.Partition(m.parallelTypeFunc)
+	parallelCtx, checkpoints, err := m.partition(ctx, pr)
 	if err != nil {
-		return 0, 0, er.WrapOp(err, op)
+		return jr.end(), er.WrapOp(err, op)
 	}
 
-	wm := monitoring.NewMonitoring(len(parallelCtx))
+	// the writer of delete_insert fails at the first chunk, so fail before any partition starts
+	if err := m.checkDeleteRange(parallelCtx); err != nil {
+		log.Error().Err(err).Msg("invalid partitions")
+		return jr.end(), er.WrapOpAndKind(err, op, er.KindFatal)
+	}
 
-	now := time.Now()
+	jr.add(parallelCtx)
+
+	wm := monitoring.NewMonitoringWithCollector(len(parallelCtx), m.workerOpt.collector)
 
 	log.Info().Int("GOMAXPROCS", runtime.GOMAXPROCS(runtime.NumCPU())).Msg("[worker monitoring] set GOMAXPROCS")
 
-	for _, parCtx := range parallelCtx {
-		go m.execute(context.Background(), parCtx, wm)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := m.dispatch(ctx, parallelCtx, checkpoints, wm, jr)
+
+	var firstErr error
+
+	receive := func(r monitoring.RowCountLog) {
+		total := jr.receive(r)
+		log.Info().Msgf("[worker monitoring] received from [%s]. totalRow: %v, totalAffected: %v, totalSkipped: %v, elapsed time : %s", r.ContextName(), total.TotalRow, total.TotalAffected, total.TotalSkipped, time.Now().Sub(now))
 	}
 
 	for {
 		select {
 		case err := <-wm.ReceiveErr():
-			log.Error().Err(err).Msg("[worker monitoring] occurred error")
-			return 0, 0, er.WrapOp(err, op)
+			if firstErr != nil {
+				log.Debug().Err(err).Msg("[worker monitoring] partition stopped")
+				continue
+			}
+			log.Error().Err(err).Msg("[worker monitoring] occurred error. waiting for the other partitions to stop")
+			firstErr = err
+			cancel()
 		case r := <-wm.ReceiveResult():
-			atomic.AddInt64(&totalAffected, r.RowAffectedCount())
-			atomic.AddInt64(&totalRow, r.RowCount())
-			log.Info().Msgf("[worker monitoring] received from [%s]. totalRow: %v, totalAffected: %v, elapsed time : %s", r.ContextName(), totalRow, totalAffected, time.Now().Sub(now))
-			if wm.IsFinish() {
-				return totalRow, totalAffected, nil
+			receive(r)
+		case <-done:
+			// results sent right before the partitions exited
+			for len(wm.ReceiveResult()) > 0 {
+				receive(<-wm.ReceiveResult())
 			}
+
+			result := jr.end()
+
+			log.Info().Msgf("[worker monitoring] end of run. written: %v, affected: %v, skipped: %v", result.TotalRow, result.TotalAffected, result.TotalSkipped)
+
+			// ctx can be cancelled while no partition is running, leaving the queued ones not started
+			if firstErr == nil && ctx.Err() != nil {
+				firstErr = ctx.Err()
+			}
+
+			if firstErr != nil {
+				return result, er.WrapOp(firstErr, op)
+			}
+
+			if repo := m.workerOpt.repository; repo != nil {
+				if err := repo.FinishJob(context.Background(), m.workerOpt.jobName); err != nil {
+					return result, er.WrapOp(err, op)
+				}
+			}
+
+			return result, nil
 		}
 	}
 }
@@ -104,37 +198,244 @@ func (m *worker[T, R, K, J]) step() (step.Step, error) {
 
 	var newReader step.NewReader[T, R, J]
 
-	switch m.stepOpt.ReaderType() {
-	case step.PagingRead:
+	switch {
+	case m.source != nil:
+		newReader = reader.NewCursorReader[T, R, J](m.source)
+	case m.stepOpt.ReaderType() == step.PagingRead:
 		newReader = reader.NewPagingDocReader[T, R, J](m.stepOpt.PageSize(), m.workerOpt.query, m.readDB)
-	case step.FullRead:
+	case m.stepOpt.ReaderType() == step.FullRead:
 		newReader = reader.NewFullDocReader[T, R, J](m.workerOpt.query, m.readDB)
+	case m.stepOpt.ReaderType() == step.KeysetRead:
+		newReader = reader.NewKeysetDocReader[T, R, J](m.stepOpt.PageSize(), m.workerOpt.query, m.readDB)
 	default:
 		return nil, er.WrapOp(errors.New("need to set required settings [step.ReaderType]"), op)
 	}
 
 	switch m.workerOpt.workerType {
 	case consumer:
-		return step.NewStep[T, R, K, J](
-			m.stepOpt.ChunkSize(),
+		w := writer.NewSqlxDocWriterWithOption[R, K](m.workerOpt.writeQuery, m.writeDB, writer.SqlxWriterOption{
+			DeleteQuery: m.workerOpt.deleteQuery,
+			Bulk:        m.workerOpt.bulkWrite,
+			CopyTable:   m.workerOpt.copyTable,
+		})
+
+		return step.NewStepWithOption[T, R, K, J](
+			m.stepOpt,
+			newReader.New(),
+			m.processorParam,
+			processor.NewProcessor[T, R, J](),
+			w), nil
+	case indexer:
+		return step.NewStepWithOption[T, R, K, J](
+			m.stepOpt,
+			newReader.New(),
+			m.processorParam,
+			processor.NewIndexerProcessor[T, R, J](m.workerOpt.countryCode),
+			writer.NewIndexDocWriter[R, K](m.workerOpt.destIndexName, m.writeDB)), nil
+	case writerWorker:
+		newWriter, ok := m.workerOpt.newWriter.(func() step.Writer[R, K])
+		if !ok {
+			return nil, er.WrapOp(errors.Errorf("%T doesn't return step.Writer of the worker", m.workerOpt.newWriter), op)
+		}
+
+		return step.NewStepWithOption[T, R, K, J](
+			m.stepOpt,
 			newReader.New(),
 			m.processorParam,
 			processor.NewProcessor[T, R, J](),
-			writer.NewSqlxDocWriter[R, K]("insert query here", m.writeDB)), nil
+			newWriter()), nil
 	default:
 		return nil, er.WrapOp(errEmptyStep, op)
 	}
 }
 
-func (m *worker[T, R, K, J]) execute(ctx context.Context, partCtx parallel.Partition, wm monitoring.WorkerMonitoring) {
+// dispatch runs the partitions on the executors pulling from a queue and returns the channel closed when all of them exit
+// The executors don't start the partitions left in the queue after ctx is cancelled
+func (m *worker[T, R, K, J]) dispatch(ctx context.Context, parallelCtx []parallel.Partition, checkpoints map[string]step.Checkpoint, wm monitoring.WorkerMonitoring, jr *jobResultRecorder) <-chan struct{} {
+	concurrency := m.workerOpt.concurrency
+	if concurrency <= 0 || concurrency > len(parallelCtx) {
+		concurrency = len(parallelCtx)
+	}
 
-	clone, err := m.step()
+	log.Info().Msgf("[worker monitoring] run %d partitions with %d executors", len(parallelCtx), concurrency)
+
+	var running *runningPartitions
+	if m.workerOpt.workStealing {
+		running = newRunningPartitions()
+	}
+
+	queue := make(chan parallel.Partition, len(parallelCtx))
+	for _, parCtx := range parallelCtx {
+		if running != nil && parCtx.Type() == parallel.AutoIncrementIdType {
+			parCtx = parallel.NewSplitPartition(parCtx)
+		}
+		queue <- parCtx
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for parCtx := range queue {
+				if ctx.Err() != nil {
+					log.Info().Msgf("[%s] not started. job is stopping", parCtx.PartitionName())
+					continue
+				}
+				m.executeRunning(ctx, parCtx, checkpoints[parCtx.PartitionName()], wm, jr, running)
+			}
+
+			// an idle executor takes over the remainder of a running partition
+			for running != nil {
+				stolen := m.steal(ctx, running, jr)
+				if stolen == nil {
+					return
+				}
+				m.executeRunning(ctx, stolen, step.Checkpoint{}, wm, jr, running)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	return done
+}
+
+// checkDeleteRange returns an error if the partitions of parallelTypeFunc are not the ranges deleted by DeleteInsertMode
+func (m *worker[T, R, K, J]) checkDeleteRange(parallelCtx []parallel.Partition) error {
+	if m.workerOpt.deleteQuery == "" {
+		return nil
+	}
+
+	for _, p := range parallelCtx {
+		if !writer.CanDeleteRange(p.Type()) {
+			return errors.Errorf("delete_insert cannot delete the range of %s partition of [%s]", parallel.TypeName(p.Type()), m.parallelTypeFunc.Name())
+		}
+	}
+	return nil
+}
+
+// partition returns the partitions to run with their checkpoints
+// With JobRepository, the partitions of the unfinished run are restored and the finished ones are skipped
+func (m *worker[T, R, K, J]) partition(ctx context.Context, pr parallel.Parallel) ([]parallel.Partition, map[string]step.Checkpoint, error) {
+	op := er.GetOperator()
+
+	repo := m.workerOpt.repository
+	if repo == nil {
+		parallelCtx, err := pr.Partition(m.parallelTypeFunc)
+		if err != nil {
+			return nil, nil, er.WrapOp(err, op)
+		}
+		return parallelCtx, nil, nil
+	}
+
+	states, err := repo.GetPartitions(ctx, m.workerOpt.jobName)
 	if err != nil {
-		wm.SendErr(err)
-		return
+		return nil, nil, er.WrapOp(err, op)
+	}
+
+	if len(states) == 0 {
+		parallelCtx, err := pr.Partition(m.parallelTypeFunc)
+		if err != nil {
+			return nil, nil, er.WrapOp(err, op)
+		}
+
+		if err := repo.SavePartitions(ctx, m.workerOpt.jobName, parallelCtx); err != nil {
+			return nil, nil, er.WrapOp(err, op)
+		}
+		return parallelCtx, nil, nil
+	}
+
+	var parallelCtx []parallel.Partition
+	checkpoints := make(map[string]step.Checkpoint, len(states))
+
+	for _, ps := range states {
+		if ps.Finished {
+			log.Info().Msgf("[%s] skip finished partition of job [%s]", ps.Name, m.workerOpt.jobName)
+			continue
+		}
+
+		parallelCtx = append(parallelCtx, ps.Partition())
+		checkpoints[ps.Name] = ps.Checkpoint
+	}
+
+	log.Info().Msgf("[worker monitoring] restart job [%s]. %d of %d partitions remain", m.workerOpt.jobName, len(parallelCtx), len(states))
+
+	return parallelCtx, checkpoints, nil
+}
+
+// executeRunning runs partCtx registered in running while it can be split
+func (m *worker[T, R, K, J]) executeRunning(ctx context.Context, partCtx parallel.Partition, cp step.Checkpoint, wm monitoring.WorkerMonitoring, jr *jobResultRecorder, running *runningPartitions) {
+	if sp, ok := partCtx.(parallel.SplitPartition); ok && running != nil {
+		running.add(sp)
+		defer running.remove(sp.PartitionName())
 	}
 
-	if err := clone.Proceed(ctx, partCtx, wm); err != nil {
+	m.execute(ctx, partCtx, cp, wm, jr)
+}
+
+func (m *worker[T, R, K, J]) execute(ctx context.Context, partCtx parallel.Partition, cp step.Checkpoint, wm monitoring.WorkerMonitoring, jr *jobResultRecorder) {
+	start := time.Now()
+
+	wm.Collector().PartitionStarted(partCtx.PartitionName())
+	defer wm.Collector().PartitionFinished(partCtx.PartitionName())
+
+	err := m.run(ctx, partCtx, cp, wm)
+
+	switch {
+	case err == nil:
+		jr.finish(partCtx.PartitionName(), start, PartitionCompleted, nil)
+	case ctx.Err() != nil:
+		jr.finish(partCtx.PartitionName(), start, PartitionStopped, err)
+	default:
+		jr.finish(partCtx.PartitionName(), start, PartitionFailed, err)
+	}
+
+	if err != nil {
 		wm.SendErr(err)
 	}
 }
+
+func (m *worker[T, R, K, J]) run(ctx context.Context, partCtx parallel.Partition, cp step.Checkpoint, wm monitoring.WorkerMonitoring) error {
+	op := er.GetOperator()
+
+	clone, err := m.step()
+	if err != nil {
+		return err
+	}
+
+	repo := m.workerOpt.repository
+	if repo == nil {
+		return clone.Proceed(ctx, partCtx, wm)
+	}
+
+	rs, ok := clone.(step.RestartableStep)
+	if !ok {
+		return er.WrapOp(errors.New("step cannot restart"), op)
+	}
+
+	store := checkpointStore{repo: repo, jobName: m.workerOpt.jobName}
+	if err := rs.ProceedFrom(ctx, partCtx, wm, cp, store); err != nil {
+		return err
+	}
+
+	if err := repo.FinishPartition(context.Background(), m.workerOpt.jobName, partCtx.PartitionName()); err != nil {
+		return er.WrapOp(err, op)
+	}
+
+	return nil
+}
+
+type checkpointStore struct {
+	repo    repository.JobRepository
+	jobName string
+}
+
+func (cs checkpointStore) SaveCheckpoint(partitionName string, cp step.Checkpoint) error {
+	return cs.repo.SaveCheckpoint(context.Background(), cs.jobName, partitionName, cp)
+}
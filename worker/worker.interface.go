@@ -1,12 +1,14 @@
 package worker
 
 import (
+	"context"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
 	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
 )
 
 type Worker interface {
-	Handle(pr parallel.Parallel, opt workerOption) (int64, int64, error)
+	Handle(pr parallel.Parallel, opt workerOption) (JobResult, error)
+	HandleContext(ctx context.Context, pr parallel.Parallel, opt workerOption) (JobResult, error)
 	ParallelDB() step.ReaderDB
 	GetReadQuery(string) string
 }
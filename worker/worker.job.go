@@ -0,0 +1,205 @@
+package worker
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// JobEnd is the transition that ends the job without running the next steps
+const JobEnd = "END"
+
+type StepStatus string
+
+const (
+	StepCompleted StepStatus = "COMPLETED"
+	StepFailed    StepStatus = "FAILED"
+	StepSkipped   StepStatus = "SKIPPED"
+)
+
+type StepResult struct {
+	Name      string     `json:"name"`
+	Status    StepStatus `json:"status"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   time.Time  `json:"end_time"`
+	Result    *JobResult `json:"result,omitempty"` // only the steps created by NewWorkerStep
+	Error     string     `json:"error,omitempty"`
+}
+
+// FlowResult is the report of Job.Run with the result of every step in order
+type FlowResult struct {
+	Name      string       `json:"name"`
+	Status    StepStatus   `json:"status"`
+	StartTime time.Time    `json:"start_time"`
+	EndTime   time.Time    `json:"end_time"`
+	Steps     []StepResult `json:"steps"`
+}
+
+type JobStep struct {
+	name      string
+	run       func(ctx context.Context) (*JobResult, error)
+	onSuccess string
+	onFailure string
+}
+
+// NewWorkerStep returns JobStep that runs w with its own partitioning, step.Option and workerOption
+func NewWorkerStep(name string, w Worker, pr parallel.Parallel, opt workerOption) *JobStep {
+	return &JobStep{
+		name: name,
+		run: func(ctx context.Context) (*JobResult, error) {
+			result, err := w.HandleContext(ctx, pr, opt)
+			return &result, err
+		},
+	}
+}
+
+// NewTaskletStep returns JobStep that runs f once (example. swapping tables after staging)
+func NewTaskletStep(name string, f func(ctx context.Context) error) *JobStep {
+	return &JobStep{
+		name: name,
+		run: func(ctx context.Context) (*JobResult, error) {
+			return nil, f(ctx)
+		},
+	}
+}
+
+// OnSuccess sets the step that runs after this step succeeds. The next step in order runs if it is not set
+func (js *JobStep) OnSuccess(next string) *JobStep {
+	js.onSuccess = next
+	return js
+}
+
+// OnFailure sets the step that runs after this step fails. The job ends if it is not set
+// The job is failed even if the step of the failure transition succeeds
+func (js *JobStep) OnFailure(next string) *JobStep {
+	js.onFailure = next
+	return js
+}
+
+// Job runs the ordered steps. The steps not reached by the transitions are skipped
+type Job struct {
+	name  string
+	steps []*JobStep
+}
+
+func NewJob(name string, steps ...*JobStep) *Job {
+	return &Job{
+		name:  name,
+		steps: steps,
+	}
+}
+
+func (j *Job) Run(ctx context.Context) (FlowResult, error) {
+	op := er.GetOperator()
+
+	result := FlowResult{
+		Name:      j.name,
+		StartTime: time.Now(),
+		Steps:     make([]StepResult, len(j.steps)),
+	}
+
+	index, err := j.validate()
+	if err != nil {
+		result.Status = StepFailed
+		result.EndTime = time.Now()
+		return result, er.WrapOpAndKind(err, op, er.KindFatal)
+	}
+
+	for i, js := range j.steps {
+		result.Steps[i] = StepResult{Name: js.name, Status: StepSkipped}
+	}
+
+	var firstErr error
+
+	for i := 0; i < len(j.steps); {
+		if err := ctx.Err(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			break
+		}
+
+		js := j.steps[i]
+		sr := &result.Steps[i]
+
+		if sr.Status != StepSkipped {
+			firstErr = errors.Errorf("step [%s] cannot run twice", js.name)
+			break
+		}
+
+		log.Info().Msgf("[%s] start step [%s]", j.name, js.name)
+
+		sr.StartTime = time.Now()
+		stepResult, err := js.run(ctx)
+		sr.EndTime = time.Now()
+		sr.Result = stepResult
+
+		next, transition := i+1, js.onSuccess
+		if err != nil {
+			log.Error().Err(err).Msgf("[%s] failed step [%s]", j.name, js.name)
+
+			sr.Status = StepFailed
+			sr.Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			transition = js.onFailure
+			if transition == "" {
+				break
+			}
+		} else {
+			sr.Status = StepCompleted
+		}
+
+		if transition == JobEnd {
+			break
+		}
+		if transition != "" {
+			next = index[transition]
+		}
+
+		i = next
+	}
+
+	result.EndTime = time.Now()
+	result.Status = StepCompleted
+
+	if firstErr != nil {
+		result.Status = StepFailed
+		return result, er.WrapOp(firstErr, op)
+	}
+
+	return result, nil
+}
+
+// validate returns the index of the steps by name after checking the names and the transitions
+func (j *Job) validate() (map[string]int, error) {
+	index := make(map[string]int, len(j.steps))
+
+	for i, js := range j.steps {
+		if js.name == "" || js.name == JobEnd {
+			return nil, errors.Errorf("invalid step name [%s]", js.name)
+		}
+		if _, ok := index[js.name]; ok {
+			return nil, errors.Errorf("duplicated step name [%s]", js.name)
+		}
+		index[js.name] = i
+	}
+
+	for _, js := range j.steps {
+		for _, next := range []string{js.onSuccess, js.onFailure} {
+			if next == "" || next == JobEnd {
+				continue
+			}
+			if _, ok := index[next]; !ok {
+				return nil, errors.Errorf("step [%s] has transition to unknown step [%s]", js.name, next)
+			}
+		}
+	}
+
+	return index, nil
+}
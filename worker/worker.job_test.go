@@ -0,0 +1,144 @@
+package worker
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Job(t *testing.T) {
+	t.Run("run steps in order", func(t *testing.T) {
+		var ran []string
+
+		job := NewJob("faqs",
+			NewTaskletStep("stage", tasklet(&ran, "stage", nil)),
+			NewTaskletStep("transform", tasklet(&ran, "transform", nil)),
+			NewTaskletStep("swap", tasklet(&ran, "swap", nil)),
+		)
+
+		result, err := job.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"stage", "transform", "swap"}, ran)
+		assert.Equal(t, StepCompleted, result.Status)
+	})
+
+	t.Run("skip the steps after failed step", func(t *testing.T) {
+		var ran []string
+
+		job := NewJob("faqs",
+			NewTaskletStep("stage", tasklet(&ran, "stage", nil)),
+			NewTaskletStep("transform", tasklet(&ran, "transform", errors.New("failed"))),
+			NewTaskletStep("swap", tasklet(&ran, "swap", nil)),
+		)
+
+		result, err := job.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, []string{"stage", "transform"}, ran)
+		assert.Equal(t, StepFailed, result.Status)
+		assert.Equal(t, StepFailed, result.Steps[1].Status)
+		assert.Equal(t, "failed", result.Steps[1].Error)
+		assert.Equal(t, StepSkipped, result.Steps[2].Status)
+	})
+
+	t.Run("follow conditional transitions", func(t *testing.T) {
+		var ran []string
+
+		job := NewJob("faqs",
+			NewTaskletStep("stage", tasklet(&ran, "stage", errors.New("failed"))).OnFailure("cleanup"),
+			NewTaskletStep("swap", tasklet(&ran, "swap", nil)).OnSuccess(JobEnd),
+			NewTaskletStep("cleanup", tasklet(&ran, "cleanup", nil)),
+		)
+
+		result, err := job.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Equal(t, []string{"stage", "cleanup"}, ran)
+		assert.Equal(t, StepSkipped, result.Steps[1].Status)
+		assert.Equal(t, StepCompleted, result.Steps[2].Status)
+	})
+
+	t.Run("fail before running with unknown transition", func(t *testing.T) {
+		var ran []string
+
+		job := NewJob("faqs",
+			NewTaskletStep("stage", tasklet(&ran, "stage", nil)).OnSuccess("unknown"),
+		)
+
+		_, err := job.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Empty(t, ran)
+	})
+}
+
+func Test_WorkerStep(t *testing.T) {
+	t.Run("run the worker and record its result", func(t *testing.T) {
+		var ran []string
+
+		db := newFaqsDBMock(1, 10)
+		client := &indexClientMock{}
+
+		job := NewJob("faqs",
+			NewWorkerStep("index", newIndexerWorker(db, client), parallel.NewParallel("faqs", db, 2),
+				IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR")),
+			NewTaskletStep("swap", tasklet(&ran, "swap", nil)),
+		)
+
+		result, err := job.Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, StepCompleted, result.Status)
+		assert.Equal(t, []string{"swap"}, ran)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, client.ids("faqs_kr"))
+
+		index := result.Steps[0]
+		assert.Equal(t, StepCompleted, index.Status)
+		if assert.NotNil(t, index.Result) {
+			assert.Equal(t, int64(10), index.Result.TotalRow)
+			assert.Len(t, index.Result.Partitions, 2)
+		}
+		assert.Nil(t, result.Steps[1].Result)
+	})
+
+	t.Run("skip the steps after failed worker", func(t *testing.T) {
+		var ran []string
+
+		db := newFaqsDBMock(1, 10)
+		db.query = func(context.Context, int64, int64) error {
+			return errors.New("connection refused")
+		}
+
+		job := NewJob("faqs",
+			NewWorkerStep("index", newIndexerWorker(db, &indexClientMock{}), parallel.NewParallel("faqs", db, 2),
+				IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR")),
+			NewTaskletStep("swap", tasklet(&ran, "swap", nil)),
+		)
+
+		result, err := job.Run(context.Background())
+
+		assert.Error(t, err)
+		assert.Empty(t, ran)
+		assert.Equal(t, StepFailed, result.Status)
+
+		index := result.Steps[0]
+		assert.Equal(t, StepFailed, index.Status)
+		assert.NotEmpty(t, index.Error)
+		if assert.NotNil(t, index.Result) {
+			for _, p := range index.Result.Partitions {
+				assert.NotEqual(t, PartitionCompleted, p.Status)
+			}
+		}
+		assert.Equal(t, StepSkipped, result.Steps[1].Status)
+	})
+}
+
+func tasklet(ran *[]string, name string, err error) func(context.Context) error {
+	return func(context.Context) error {
+		*ran = append(*ran, name)
+		return err
+	}
+}
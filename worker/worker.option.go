@@ -1,10 +1,29 @@
 package worker
 
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/dialect"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/repository"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/pkg/errors"
+)
+
 type workerType string
 
+// WriteMode is how the consumer writer generated by WithDestTable treats the rows written before
+type WriteMode string
+
+const (
+	InsertMode       WriteMode = "insert"        // inserts every row
+	InsertIgnoreMode WriteMode = "insert_ignore" // ignores the rows conflicting with a unique key
+	UpsertMode       WriteMode = "upsert"        // updates the rows conflicting with the key columns
+	DeleteInsertMode WriteMode = "delete_insert" // deletes the range of the partition of the key column and inserts
+)
+
 var (
-	consumer workerType = "consumer"
-	indexer  workerType = "indexer"
+	consumer     workerType = "consumer"
+	indexer      workerType = "indexer"
+	writerWorker workerType = "writer"
 )
 
 type workerOption struct {
@@ -15,6 +34,21 @@ type workerOption struct {
 	destIndexName string
 	sourceName    string
 	columns       []string
+	writeQuery    string
+	destTable     string
+	dialect       dialect.Dialect
+	writeMode     WriteMode
+	keyColumns    []string
+	deleteQuery   string
+	bulkWrite     bool
+	copyTable     string
+	repository    repository.JobRepository
+	jobName       string
+	concurrency   int
+	collector     monitoring.Collector
+	workStealing  bool
+	stealMinSize  int64
+	newWriter     any // func() step.Writer[R, K] of WriterWorkerOptions
 }
 
 func ConsumerWorkerOptions(readQuery, sourceName string, columns []string) workerOption {
@@ -39,3 +73,161 @@ func IndexerWorkerOptions(readQuery, destIndexName, countryCode string) workerOp
 	}
 	return op
 }
+
+// WriterWorkerOptions writes the partitions with the writers newWriter returns (example. writer.NewFileDocWriter, writer.NewHttpDocWriter)
+// newWriter is called for every partition, and R and K must be the types of the worker
+func WriterWorkerOptions[R, K any](newWriter func() step.Writer[R, K]) workerOption {
+	return workerOption{
+		isSet:      true,
+		workerType: writerWorker,
+		newWriter:  newWriter,
+	}
+}
+
+// WithWriteQuery sets the statement executed by the consumer writer (example. "INSERT INTO faqs (id, title) VALUES (:id, :title)")
+func (wo workerOption) WithWriteQuery(writeQuery string) workerOption {
+	wo.writeQuery = writeQuery
+	return wo
+}
+
+// WithDestTable generates the insert statement of the consumer writer from columns for destTable
+// It is ignored when WithWriteQuery is set
+func (wo workerOption) WithDestTable(destTable string, d dialect.Dialect) workerOption {
+	wo.destTable = destTable
+	wo.dialect = d
+	return wo
+}
+
+// WithWriteMode makes the reruns of the consumer idempotent. keyColumns are the conflict target of UpsertMode or the range column of DeleteInsertMode
+// The statements are generated for the dialect of WithDestTable
+func (wo workerOption) WithWriteMode(mode WriteMode, keyColumns ...string) workerOption {
+	wo.writeMode = mode
+	wo.keyColumns = keyColumns
+	return wo
+}
+
+// WithBulkWrite inserts the chunks of the consumer with multi-row statements split under the placeholder limit of the driver
+// The insert generated for a Postgres destTable is loaded by COPY FROM STDIN with the lib/pq driver
+func (wo workerOption) WithBulkWrite() workerOption {
+	wo.bulkWrite = true
+	return wo
+}
+
+// WithJobRepository saves the partitions and their checkpoints of jobName into repo
+// When the job failed, the next run skips the finished partitions and resumes the others from their checkpoints
+func (wo workerOption) WithJobRepository(repo repository.JobRepository, jobName string) workerOption {
+	wo.repository = repo
+	wo.jobName = jobName
+	return wo
+}
+
+// WithConcurrency limits the number of partitions running at the same time to concurrency
+// The partitions wait in a queue until an executor is free. It runs every partition at once if concurrency is not set
+func (wo workerOption) WithConcurrency(concurrency int) workerOption {
+	wo.concurrency = concurrency
+	return wo
+}
+
+// WithCollector passes the metrics of the partitions to collector (example. monitoring.PrometheusExporter)
+func (wo workerOption) WithCollector(collector monitoring.Collector) workerOption {
+	wo.collector = collector
+	return wo
+}
+
+// WithWorkStealing lets an idle executor split the unread remainder of a running AutoIncrementId partition and take the upper half
+// The remainder is not split below twice minSize. minSize is the page size of the step if it is not positive
+// It needs step.PagingRead. Handle fails with er.KindFatal for the other readers and NewSourceWorker
+func (wo workerOption) WithWorkStealing(minSize int64) workerOption {
+	wo.workStealing = true
+	wo.stealMinSize = minSize
+	return wo
+}
+
+// prepare validates the settings and returns workerOption filled with the generated write query
+func (wo workerOption) prepare() (workerOption, error) {
+	if wo.concurrency < 0 {
+		return wo, errors.New("concurrency must not be negative")
+	}
+
+	if wo.repository != nil && wo.jobName == "" {
+		return wo, errors.New("need to set required settings [jobName]")
+	}
+
+	switch wo.workerType {
+	case consumer:
+		return wo.prepareConsumer()
+	case indexer:
+		if wo.destIndexName == "" {
+			return wo, errors.New("need to set required settings [destIndexName]")
+		}
+	case writerWorker:
+		if wo.newWriter == nil {
+			return wo, errors.New("need to set required settings [newWriter]")
+		}
+	}
+
+	return wo, nil
+}
+
+// prepareConsumer generates the statements of the consumer writer by WriteMode
+func (wo workerOption) prepareConsumer() (workerOption, error) {
+	mode := wo.writeMode
+	if mode == "" {
+		mode = InsertMode
+	}
+
+	if mode == InsertMode && wo.writeQuery != "" {
+		return wo, nil
+	}
+
+	if wo.destTable == "" {
+		return wo, errors.New("need to set required settings [writeQuery or destTable]")
+	}
+
+	// writeQuery can replace only the insert of DeleteInsertMode
+	if wo.writeQuery != "" && mode != DeleteInsertMode {
+		return wo, errors.Errorf("write mode [%s] cannot be used with writeQuery", mode)
+	}
+
+	var q string
+	var err error
+
+	switch mode {
+	case InsertMode, DeleteInsertMode:
+		q = wo.writeQuery
+		if q == "" {
+			q, err = wo.dialect.Insert(wo.destTable, wo.columns)
+			if wo.bulkWrite && wo.dialect == dialect.Postgres {
+				wo.copyTable = wo.destTable
+			}
+		}
+	case InsertIgnoreMode:
+		q, err = wo.dialect.InsertIgnore(wo.destTable, wo.columns)
+	case UpsertMode:
+		q, err = wo.dialect.Upsert(wo.destTable, wo.columns, wo.keyColumns)
+	default:
+		return wo, errors.Errorf("unsupported write mode [%s]", mode)
+	}
+	if err != nil {
+		return wo, errors.Wrap(err, "cannot generate write query")
+	}
+
+	wo.writeQuery = q
+
+	if mode == DeleteInsertMode {
+		if len(wo.keyColumns) != 1 {
+			return wo, errors.New("delete_insert needs a key column")
+		}
+
+		// the rows written before the checkpoint would be deleted on restart
+		if wo.repository != nil {
+			return wo, errors.New("delete_insert cannot be used with JobRepository")
+		}
+
+		if wo.deleteQuery, err = wo.dialect.DeleteRange(wo.destTable, wo.keyColumns[0]); err != nil {
+			return wo, errors.Wrap(err, "cannot generate delete query")
+		}
+	}
+
+	return wo, nil
+}
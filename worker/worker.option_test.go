@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/dialect"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/repository"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_WorkerOptionWriteMode(t *testing.T) {
+	columns := []string{"id", "title"}
+
+	t.Run("generate statements of write mode", func(t *testing.T) {
+		wo, err := ConsumerWorkerOptions("", "faqs", columns).
+			WithDestTable("faqs_copy", dialect.Postgres).
+			WithWriteMode(UpsertMode, "id").
+			prepare()
+
+		assert.NoError(t, err)
+		assert.Equal(t, `INSERT INTO "faqs_copy" ("id", "title") VALUES (:id, :title) ON CONFLICT ("id") DO UPDATE SET "title" = excluded."title"`, wo.writeQuery)
+
+		wo, err = ConsumerWorkerOptions("", "faqs", columns).
+			WithDestTable("faqs_copy", dialect.MySQL).
+			WithWriteMode(DeleteInsertMode, "id").
+			prepare()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "INSERT INTO `faqs_copy` (`id`, `title`) VALUES (:id, :title)", wo.writeQuery)
+		assert.Equal(t, "DELETE FROM `faqs_copy` WHERE `id` >= :from AND `id` <= :to", wo.deleteQuery)
+	})
+
+	t.Run("fail with bad settings", func(t *testing.T) {
+		_, err := ConsumerWorkerOptions("", "faqs", columns).
+			WithWriteQuery("INSERT INTO faqs_copy (id) VALUES (:id)").
+			WithWriteMode(UpsertMode, "id").
+			prepare()
+		assert.Error(t, err)
+
+		_, err = ConsumerWorkerOptions("", "faqs", columns).
+			WithDestTable("faqs_copy", dialect.Postgres).
+			WithWriteMode(UpsertMode).
+			prepare()
+		assert.Error(t, err)
+
+		_, err = ConsumerWorkerOptions("", "faqs", columns).
+			WithDestTable("faqs_copy", dialect.Postgres).
+			WithWriteMode(DeleteInsertMode, "id").
+			WithJobRepository(repository.NewMemoryJobRepository(), "faqs").
+			prepare()
+		assert.Error(t, err)
+	})
+}
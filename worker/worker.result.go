@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"sync"
+	"time"
+)
+
+type PartitionStatus string
+
+const (
+	PartitionNotStarted PartitionStatus = "NOT_STARTED"
+	PartitionCompleted  PartitionStatus = "COMPLETED"
+	PartitionFailed     PartitionStatus = "FAILED"
+	PartitionStopped    PartitionStatus = "STOPPED" // cancelled while running
+)
+
+type PartitionResult struct {
+	Name      string          `json:"name"`
+	Type      string          `json:"type"`
+	Min       int64           `json:"min"`    // range of the partition except parallel.SortableIdType
+	Max       int64           `json:"max"`    // range of the partition except parallel.SortableIdType
+	Limit     int64           `json:"limit"`  // only parallel.SortableIdType
+	Offset    int64           `json:"offset"` // only parallel.SortableIdType
+	Rows      int64           `json:"rows"`
+	Affected  int64           `json:"affected"`
+	Skipped   int64           `json:"skipped"`
+	StartTime time.Time       `json:"start_time"`
+	EndTime   time.Time       `json:"end_time"`
+	Duration  time.Duration   `json:"duration_ns"`
+	Status    PartitionStatus `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	SplitFrom string          `json:"split_from,omitempty"` // partition whose remainder was stolen by WithWorkStealing
+}
+
+// JobResult is the report of a run returned by Handle. It can be serialized by encoding/json
+type JobResult struct {
+	Strategy      string            `json:"strategy"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	TotalRow      int64             `json:"total_row"`
+	TotalAffected int64             `json:"total_affected"`
+	TotalSkipped  int64             `json:"total_skipped"`
+	Partitions    []PartitionResult `json:"partitions"`
+}
+
+// jobResultRecorder builds JobResult from the partitions running at the same time
+type jobResultRecorder struct {
+	mu     sync.Mutex
+	result JobResult
+	index  map[string]int
+}
+
+func newJobResultRecorder(strategy string, start time.Time) *jobResultRecorder {
+	return &jobResultRecorder{
+		result: JobResult{
+			Strategy:  strategy,
+			StartTime: start,
+		},
+		index: make(map[string]int),
+	}
+}
+
+func (jrr *jobResultRecorder) add(partitions []parallel.Partition) {
+	jrr.mu.Lock()
+	defer jrr.mu.Unlock()
+
+	for _, p := range partitions {
+		pr := PartitionResult{
+			Name:   p.PartitionName(),
+			Type:   parallel.TypeName(p.Type()),
+			Status: PartitionNotStarted,
+		}
+
+		if p.Type() == parallel.SortableIdType {
+			pr.Limit, pr.Offset = p.Min(), p.Max()
+		} else {
+			pr.Min, pr.Max = p.Min(), p.Max()
+		}
+
+		jrr.index[pr.Name] = len(jrr.result.Partitions)
+		jrr.result.Partitions = append(jrr.result.Partitions, pr)
+	}
+}
+
+// split shrinks the range of partitionName to max and adds sub split from it
+func (jrr *jobResultRecorder) split(partitionName string, max int64, sub parallel.Partition) {
+	jrr.add([]parallel.Partition{sub})
+
+	jrr.mu.Lock()
+	defer jrr.mu.Unlock()
+
+	if i, ok := jrr.index[partitionName]; ok {
+		jrr.result.Partitions[i].Max = max
+	}
+	jrr.result.Partitions[jrr.index[sub.PartitionName()]].SplitFrom = partitionName
+}
+
+func (jrr *jobResultRecorder) receive(r monitoring.RowCountLog) JobResult {
+	jrr.mu.Lock()
+	defer jrr.mu.Unlock()
+
+	jrr.result.TotalRow += r.RowCount()
+	jrr.result.TotalAffected += r.RowAffectedCount()
+	jrr.result.TotalSkipped += r.SkipCount()
+
+	if i, ok := jrr.index[r.ContextName()]; ok {
+		pr := &jrr.result.Partitions[i]
+		pr.Rows += r.RowCount()
+		pr.Affected += r.RowAffectedCount()
+		pr.Skipped += r.SkipCount()
+	}
+
+	return jrr.result
+}
+
+func (jrr *jobResultRecorder) finish(partitionName string, start time.Time, status PartitionStatus, err error) {
+	jrr.mu.Lock()
+	defer jrr.mu.Unlock()
+
+	i, ok := jrr.index[partitionName]
+	if !ok {
+		return
+	}
+
+	pr := &jrr.result.Partitions[i]
+	pr.StartTime = start
+	pr.EndTime = time.Now()
+	pr.Duration = pr.EndTime.Sub(start)
+	pr.Status = status
+	if err != nil {
+		pr.Error = err.Error()
+	}
+}
+
+func (jrr *jobResultRecorder) end() JobResult {
+	jrr.mu.Lock()
+	defer jrr.mu.Unlock()
+
+	jrr.result.EndTime = time.Now()
+
+	partitions := make([]PartitionResult, len(jrr.result.Partitions))
+	copy(partitions, jrr.result.Partitions)
+
+	result := jrr.result
+	result.Partitions = partitions
+
+	return result
+}
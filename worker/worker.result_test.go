@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"encoding/json"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/monitoring"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_JobResult(t *testing.T) {
+	t.Run("report stats of each partition as json", func(t *testing.T) {
+		jr := newJobResultRecorder("SortableId", time.Now())
+		jr.add([]parallel.Partition{
+			parallel.RestorePartition("pCtx0", 301, 0, 0, parallel.SortableIdType),
+			parallel.RestorePartition("pCtx1", 301, 301, 0, parallel.SortableIdType),
+			parallel.RestorePartition("pCtx2", 301, 602, 0, parallel.SortableIdType),
+		})
+
+		jr.receive(monitoring.NewSkipCountLog("pCtx0", 290, 300, 1))
+		jr.receive(monitoring.NewRowCountLog("pCtx1", 10, 10))
+		jr.finish("pCtx0", time.Now(), PartitionCompleted, nil)
+		jr.finish("pCtx1", time.Now(), PartitionFailed, errors.New("write failed"))
+
+		result := jr.end()
+
+		assert.Equal(t, int64(310), result.TotalRow)
+		assert.Equal(t, int64(300), result.TotalAffected)
+		assert.Equal(t, int64(1), result.TotalSkipped)
+
+		assert.Equal(t, PartitionCompleted, result.Partitions[0].Status)
+		assert.Equal(t, int64(301), result.Partitions[1].Limit)
+		assert.Equal(t, int64(301), result.Partitions[1].Offset)
+		assert.Equal(t, "write failed", result.Partitions[1].Error)
+		assert.Equal(t, PartitionNotStarted, result.Partitions[2].Status)
+
+		b, err := json.Marshal(result)
+		assert.NoError(t, err)
+
+		var decoded JobResult
+		assert.NoError(t, json.Unmarshal(b, &decoded))
+		assert.Equal(t, "SortableId", decoded.Strategy)
+		assert.Equal(t, result.Partitions[1].Error, decoded.Partitions[1].Error)
+		assert.Equal(t, "SortableId", decoded.Partitions[0].Type)
+
+		// the first partition of SortableId has the offset 0
+		p, err := json.Marshal(result.Partitions[0])
+		assert.NoError(t, err)
+		assert.Contains(t, string(p), `"limit":301,"offset":0,`)
+	})
+}
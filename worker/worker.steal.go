@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// stealInterval is how often an idle executor looks for a partition to split
+var stealInterval = 100 * time.Millisecond
+
+// runningPartitions is the set of parallel.SplitPartition being read by the executors
+type runningPartitions struct {
+	mu         sync.Mutex
+	partitions map[string]parallel.SplitPartition
+}
+
+func newRunningPartitions() *runningPartitions {
+	return &runningPartitions{
+		partitions: make(map[string]parallel.SplitPartition),
+	}
+}
+
+func (rp *runningPartitions) add(sp parallel.SplitPartition) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.partitions[sp.PartitionName()] = sp
+}
+
+func (rp *runningPartitions) remove(name string) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	delete(rp.partitions, name)
+}
+
+// largest returns the partition with the largest remainder. It returns false if no partition is running
+func (rp *runningPartitions) largest() (parallel.SplitPartition, bool) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	var largest parallel.SplitPartition
+	var remaining int64
+
+	for _, sp := range rp.partitions {
+		if r := sp.Remaining(); r > remaining {
+			largest, remaining = sp, r
+		}
+	}
+
+	return largest, len(rp.partitions) > 0
+}
+
+// steal splits the largest remainder of the running partitions and returns the upper half to run
+// It waits while the running partitions are too small to split and returns nil when none is running or ctx is cancelled
+func (m *worker[T, R, K, J]) steal(ctx context.Context, running *runningPartitions, jr *jobResultRecorder) parallel.SplitPartition {
+	minSize := m.workerOpt.stealMinSize
+	if minSize <= 0 {
+		minSize = m.stepOpt.PageSize()
+	}
+
+	for ctx.Err() == nil {
+		sp, ok := running.largest()
+		if !ok {
+			return nil
+		}
+
+		if sp != nil {
+			sub, ok, err := sp.Split(minSize, m.commitSplit(sp.PartitionName()))
+			if err != nil {
+				log.Err(err).Msgf("[%s] failed to split", sp.PartitionName())
+				return nil
+			}
+
+			if ok {
+				jr.split(sp.PartitionName(), sp.Max(), sub)
+				log.Info().Msgf("[%s] split into [max:%d] and [%s] [min:%d] [max:%d]", sp.PartitionName(), sp.Max(), sub.PartitionName(), sub.Min(), sub.Max())
+
+				stolen := parallel.NewSplitPartition(sub)
+				running.add(stolen)
+				return stolen
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(stealInterval):
+		}
+	}
+
+	return nil
+}
+
+// commitSplit saves the split into JobRepository before it is applied so that a restarted job reads neither half twice
+func (m *worker[T, R, K, J]) commitSplit(partitionName string) func(max int64, sub parallel.Partition) error {
+	repo := m.workerOpt.repository
+	if repo == nil {
+		return nil
+	}
+
+	return func(max int64, sub parallel.Partition) error {
+		return repo.SplitPartition(context.Background(), m.workerOpt.jobName, partitionName, max, sub)
+	}
+}
@@ -0,0 +1,603 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/dialect"
+	"github.com/Hoyaspark/go-partitioning-batch/pkg/er"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/parallel"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/repository"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step/reader"
+	"github.com/Hoyaspark/go-partitioning-batch/worker/step/writer"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const faqsQuery = "SELECT id, title FROM faqs WHERE id >= :from AND id <= :to"
+
+// faqsDBMock is step.ReaderDB of the faqs table of the ids [min, max]
+// It answers faqsQuery bound with :from and :to through a database/sql driver after query is called with the range of the query
+// and counts the queries running or with the rows open at the same time
+type faqsDBMock struct {
+	min, max int64
+	db       *sqlx.DB
+	query    func(ctx context.Context, from, to int64) error
+
+	mu       sync.Mutex
+	queried  []int64 // from of every query
+	inFlight int
+	peak     int
+}
+
+func newFaqsDBMock(min, max int64) *faqsDBMock {
+	m := &faqsDBMock{min: min, max: max}
+	m.db = sqlx.NewDb(sql.OpenDB(m), "mysql")
+	return m
+}
+
+func (m *faqsDBMock) GetSortBy(string) (parallel.Partition, error) {
+	return parallel.NewPartition(m.min, m.max, m.max-m.min+1), nil
+}
+
+func (m *faqsDBMock) GetReadQuery(string) string { return faqsQuery }
+func (m *faqsDBMock) ReadDB() *sqlx.DB           { return m.db }
+
+func (m *faqsDBMock) Connect(context.Context) (driver.Conn, error) { return &faqsConnMock{db: m}, nil }
+func (m *faqsDBMock) Driver() driver.Driver                        { return nil }
+
+func (m *faqsDBMock) rows(ctx context.Context, from, to int64) (driver.Rows, error) {
+	m.mu.Lock()
+	m.queried = append(m.queried, from)
+	m.inFlight++
+	if m.inFlight > m.peak {
+		m.peak = m.inFlight
+	}
+	m.mu.Unlock()
+
+	rows := &faqsRowsMock{db: m}
+
+	if m.query != nil {
+		if err := m.query(ctx, from, to); err != nil {
+			rows.Close()
+			return nil, err
+		}
+	}
+
+	for id := from; id <= to; id++ {
+		if id >= m.min && id <= m.max {
+			rows.ids = append(rows.ids, id)
+		}
+	}
+	return rows, nil
+}
+
+type faqsConnMock struct {
+	db *faqsDBMock
+}
+
+func (c *faqsConnMock) Prepare(string) (driver.Stmt, error) {
+	return &faqsStmtMock{db: c.db}, nil
+}
+
+func (c *faqsConnMock) Close() error              { return nil }
+func (c *faqsConnMock) Begin() (driver.Tx, error) { return nil, io.EOF }
+
+type faqsStmtMock struct {
+	db *faqsDBMock
+}
+
+func (s *faqsStmtMock) Close() error  { return nil }
+func (s *faqsStmtMock) NumInput() int { return -1 }
+
+func (s *faqsStmtMock) Exec([]driver.Value) (driver.Result, error) {
+	return nil, io.EOF
+}
+
+func (s *faqsStmtMock) Query(args []driver.Value) (driver.Rows, error) {
+	return s.db.rows(context.Background(), args[0].(int64), args[1].(int64))
+}
+
+func (s *faqsStmtMock) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.db.rows(ctx, args[0].Value.(int64), args[1].Value.(int64))
+}
+
+type faqsRowsMock struct {
+	db     *faqsDBMock
+	ids    []int64
+	closed bool
+}
+
+func (r *faqsRowsMock) Columns() []string { return []string{"id", "title"} }
+
+func (r *faqsRowsMock) Close() error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		r.db.inFlight--
+	}
+	return nil
+}
+
+func (r *faqsRowsMock) Next(dest []driver.Value) error {
+	if len(r.ids) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.ids[0], fmt.Sprintf("t%d", r.ids[0])
+	r.ids = r.ids[1:]
+	return nil
+}
+
+// countryParam is the processor param of the indexer receiving the country code
+type countryParam struct {
+	CountryCode string
+}
+
+func (p countryParam) GetProcessorParam() (*countryParam, error) {
+	return &p, nil
+}
+
+func (p *countryParam) SetCountryCode(countryCode string) {
+	p.CountryCode = countryCode
+}
+
+// sharedParam returns the same param to every item like a param loaded once
+type sharedParam struct {
+	param *countryParam
+}
+
+func (p sharedParam) GetProcessorParam() (*countryParam, error) {
+	return p.param, nil
+}
+
+type indexFaq struct {
+	Id    int64  `db:"id"`
+	Title string `db:"title"`
+}
+
+type indexFaqDoc struct {
+	Id          int64  `json:"id"`
+	CountryCode string `json:"country_code"`
+}
+
+func (f indexFaq) ToModel(param *countryParam) (*indexFaqDoc, error) {
+	return &indexFaqDoc{Id: f.Id, CountryCode: param.CountryCode}, nil
+}
+
+// indexClientMock is writer.IndexClient collecting the documents by the index
+type indexClientMock struct {
+	mu   sync.Mutex
+	docs map[string][]indexFaqDoc
+}
+
+func (c *indexClientMock) Bulk(_ context.Context, indexName string, docs []any) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.docs == nil {
+		c.docs = make(map[string][]indexFaqDoc)
+	}
+	for _, doc := range docs {
+		c.docs[indexName] = append(c.docs[indexName], doc.(indexFaqDoc))
+	}
+	return int64(len(docs)), nil
+}
+
+// ids returns the sorted ids of the documents of indexName
+func (c *indexClientMock) ids(indexName string) []int64 {
+	var ids []int64
+	for _, doc := range c.docs[indexName] {
+		ids = append(ids, doc.Id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+type indexStorageMock struct {
+	client *indexClientMock
+}
+
+func (m *indexStorageMock) GetClient() *indexClientMock {
+	return m.client
+}
+
+func newIndexerWorker(db *faqsDBMock, client *indexClientMock) Worker {
+	return NewWorker[indexFaq, indexFaqDoc, indexClientMock, countryParam](
+		db, &indexStorageMock{client: client}, countryParam{}, parallel.AutoIncrementId, step.DefaultPagingIndexerOption)
+}
+
+func Test_IndexerWorker(t *testing.T) {
+	t.Run("index the rows of the partitions with the country code", func(t *testing.T) {
+		db := newFaqsDBMock(1, 10)
+		client := &indexClientMock{}
+
+		result, err := newIndexerWorker(db, client).Handle(parallel.NewParallel("faqs", db, 2),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), result.TotalRow)
+		assert.Equal(t, int64(10), result.TotalAffected)
+
+		assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, client.ids("faqs_kr"))
+		for _, doc := range client.docs["faqs_kr"] {
+			assert.Equal(t, "KR", doc.CountryCode)
+		}
+	})
+
+	t.Run("not change the param shared by the processors", func(t *testing.T) {
+		db := newFaqsDBMock(1, 10)
+		param := &countryParam{}
+		client := &indexClientMock{}
+
+		w := NewWorker[indexFaq, indexFaqDoc, indexClientMock, countryParam](
+			db, &indexStorageMock{client: client}, sharedParam{param: param}, parallel.AutoIncrementId,
+			step.NewOption(step.PagingRead, 3, 2).WithPipeline(step.Pipeline{Processors: 4}))
+
+		result, err := w.Handle(parallel.NewParallel("faqs", db, 2), IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR"))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), result.TotalAffected)
+
+		assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, client.ids("faqs_kr"))
+		for _, doc := range client.docs["faqs_kr"] {
+			assert.Equal(t, "KR", doc.CountryCode)
+		}
+		assert.Empty(t, param.CountryCode)
+	})
+
+	t.Run("fail without the index name", func(t *testing.T) {
+		db := newFaqsDBMock(1, 10)
+		client := &indexClientMock{}
+
+		_, err := newIndexerWorker(db, client).Handle(parallel.NewParallel("faqs", db, 2),
+			IndexerWorkerOptions(faqsQuery, "", "KR"))
+		assert.True(t, er.IsKind(err, er.KindFatal))
+		assert.Empty(t, client.docs)
+	})
+}
+
+func Test_WorkerCancel(t *testing.T) {
+	t.Run("cancel the running partitions when a partition fails", func(t *testing.T) {
+		slowStarted := make(chan struct{})
+		var slowCancelled bool
+
+		db := newFaqsDBMock(1, 10)
+		db.query = func(ctx context.Context, from, _ int64) error {
+			if from == 1 {
+				<-slowStarted
+				return errors.New("read failed")
+			}
+
+			close(slowStarted)
+			<-ctx.Done()
+			slowCancelled = true
+			return ctx.Err()
+		}
+		client := &indexClientMock{}
+
+		result, err := newIndexerWorker(db, client).HandleContext(context.Background(), parallel.NewParallel("faqs", db, 2),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR"))
+		assert.EqualError(t, er.Parse(err).Err, "read failed")
+
+		// the slow partition has exited before HandleContext returns
+		assert.True(t, slowCancelled)
+		assert.Empty(t, client.docs)
+
+		assert.Equal(t, PartitionFailed, result.Partitions[0].Status)
+		assert.Equal(t, PartitionStopped, result.Partitions[1].Status)
+		assert.Contains(t, result.Partitions[1].Error, context.Canceled.Error())
+	})
+
+	t.Run("return the counts written until the caller cancels", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		db := newFaqsDBMock(1, 10)
+		db.query = func(qctx context.Context, from, _ int64) error {
+			if from == 6 {
+				cancel()
+				<-qctx.Done()
+				return qctx.Err()
+			}
+			return nil
+		}
+		client := &indexClientMock{}
+
+		result, err := newIndexerWorker(db, client).HandleContext(ctx, parallel.NewParallel("faqs", db, 2),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR"))
+		assert.True(t, er.Is(err, context.Canceled))
+
+		assert.Equal(t, int64(len(client.docs["faqs_kr"])), result.TotalRow)
+		assert.Equal(t, result.TotalRow, result.TotalAffected)
+		for _, doc := range client.docs["faqs_kr"] {
+			assert.LessOrEqual(t, doc.Id, int64(5))
+		}
+	})
+}
+
+func Test_WorkerRestart(t *testing.T) {
+	t.Run("skip the finished partitions and resume the others from the checkpoint", func(t *testing.T) {
+		ctx := context.Background()
+
+		repo := repository.NewMemoryJobRepository()
+		assert.NoError(t, repo.SavePartitions(ctx, "faqs", []parallel.Partition{
+			parallel.RestorePartition("pCtx0", 1, 4, 0, parallel.AutoIncrementIdType),
+			parallel.RestorePartition("pCtx1", 5, 8, 0, parallel.AutoIncrementIdType),
+			parallel.RestorePartition("pCtx2", 9, 12, 0, parallel.AutoIncrementIdType),
+		}))
+		assert.NoError(t, repo.FinishPartition(ctx, "faqs", "pCtx0"))
+		assert.NoError(t, repo.SaveCheckpoint(ctx, "faqs", "pCtx1", step.Checkpoint{Offset: 2}))
+
+		db := newFaqsDBMock(1, 12)
+		client := &indexClientMock{}
+
+		result, err := newIndexerWorker(db, client).HandleContext(ctx, parallel.NewParallel("faqs", db, 3),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR").WithJobRepository(repo, "faqs"))
+		assert.NoError(t, err)
+
+		assert.Equal(t, []int64{7, 8, 9, 10, 11, 12}, client.ids("faqs_kr"))
+		assert.Equal(t, int64(6), result.TotalRow)
+
+		// the finished job is removed
+		states, err := repo.GetPartitions(ctx, "faqs")
+		assert.NoError(t, err)
+		assert.Empty(t, states)
+	})
+
+	t.Run("keep the partitions of the failed job", func(t *testing.T) {
+		ctx := context.Background()
+		repo := repository.NewMemoryJobRepository()
+
+		db := newFaqsDBMock(1, 12)
+		db.query = func(context.Context, int64, int64) error {
+			return errors.New("read failed")
+		}
+
+		_, err := newIndexerWorker(db, &indexClientMock{}).HandleContext(ctx, parallel.NewParallel("faqs", db, 3),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR").WithJobRepository(repo, "faqs"))
+		assert.Error(t, err)
+
+		states, err := repo.GetPartitions(ctx, "faqs")
+		assert.NoError(t, err)
+		assert.Len(t, states, 3)
+		for _, ps := range states {
+			assert.False(t, ps.Finished)
+		}
+	})
+}
+
+func Test_WorkerConcurrency(t *testing.T) {
+	t.Run("run the partitions more than the executors", func(t *testing.T) {
+		db := newFaqsDBMock(1, 12)
+		db.query = func(context.Context, int64, int64) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}
+		client := &indexClientMock{}
+
+		result, err := newIndexerWorker(db, client).HandleContext(context.Background(), parallel.NewParallel("faqs", db, 6),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR").WithConcurrency(2))
+		assert.NoError(t, err)
+
+		assert.Equal(t, 2, db.peak)
+		assert.Len(t, db.queried, 6)
+		assert.Equal(t, int64(12), result.TotalRow)
+
+		assert.Len(t, result.Partitions, 6)
+		for _, p := range result.Partitions {
+			assert.Equal(t, PartitionCompleted, p.Status)
+			assert.Equal(t, int64(2), p.Rows)
+		}
+		assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}, client.ids("faqs_kr"))
+	})
+
+	t.Run("not start the queued partitions after cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		db := newFaqsDBMock(1, 12)
+		db.query = func(_ context.Context, from, _ int64) error {
+			if from == 3 {
+				cancel()
+			}
+			return nil
+		}
+
+		result, err := newIndexerWorker(db, &indexClientMock{}).HandleContext(ctx, parallel.NewParallel("faqs", db, 6),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR").WithConcurrency(1))
+		assert.True(t, er.Is(err, context.Canceled))
+
+		assert.Equal(t, []int64{1, 3}, db.queried)
+
+		assert.Equal(t, PartitionCompleted, result.Partitions[0].Status)
+		assert.Equal(t, PartitionStopped, result.Partitions[1].Status)
+		for _, p := range result.Partitions[2:] {
+			assert.Equal(t, PartitionNotStarted, p.Status)
+		}
+	})
+
+	t.Run("fail with negative concurrency", func(t *testing.T) {
+		db := newFaqsDBMock(1, 12)
+
+		_, err := newIndexerWorker(db, &indexClientMock{}).HandleContext(context.Background(), parallel.NewParallel("faqs", db, 6),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR").WithConcurrency(-1))
+		assert.True(t, er.IsKind(err, er.KindFatal))
+		assert.Empty(t, db.queried)
+	})
+}
+
+func Test_WorkerWorkStealing(t *testing.T) {
+	t.Run("split the remainder of the running partition for the idle executor", func(t *testing.T) {
+		defer func(interval time.Duration) { stealInterval = interval }(stealInterval)
+		stealInterval = time.Millisecond
+
+		db := newFaqsDBMock(1, 20)
+		db.query = func(_ context.Context, from, _ int64) error {
+			if from > 10 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			return nil
+		}
+		client := &indexClientMock{}
+		w := NewWorker[indexFaq, indexFaqDoc, indexClientMock, countryParam](
+			db, &indexStorageMock{client: client}, countryParam{}, parallel.AutoIncrementId, step.NewOption(step.PagingRead, 2, 2))
+
+		result, err := w.HandleContext(context.Background(), parallel.NewParallel("faqs", db, 2),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR").WithWorkStealing(2))
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(20), result.TotalRow)
+		var ids []int64
+		for id := int64(1); id <= 20; id++ {
+			ids = append(ids, id)
+		}
+		assert.Equal(t, ids, client.ids("faqs_kr"))
+
+		var split []PartitionResult
+		for _, p := range result.Partitions {
+			assert.Equal(t, PartitionCompleted, p.Status)
+			if p.SplitFrom != "" {
+				split = append(split, p)
+			}
+		}
+		if assert.NotEmpty(t, split) {
+			assert.Equal(t, int64(20), split[0].Max)
+		}
+	})
+
+	t.Run("fail with the reader not claiming the pages", func(t *testing.T) {
+		db := newFaqsDBMock(1, 20)
+		w := NewWorker[indexFaq, indexFaqDoc, indexClientMock, countryParam](
+			db, &indexStorageMock{client: &indexClientMock{}}, countryParam{}, parallel.AutoIncrementId, step.DefaultKeysetIndexerOption)
+
+		_, err := w.HandleContext(context.Background(), parallel.NewParallel("faqs", db, 2),
+			IndexerWorkerOptions(faqsQuery, "faqs_kr", "KR").WithWorkStealing(2))
+		assert.True(t, er.IsKind(err, er.KindFatal))
+		assert.Empty(t, db.queried)
+	})
+}
+
+func Test_DeleteInsertWorker(t *testing.T) {
+	t.Run("fail before any partition starts with the partitions without the key range", func(t *testing.T) {
+		db := newFaqsDBMock(1, 12)
+		w := NewWorker[faqDoc, faqDoc, sqlx.DB, step.EmptyDocProcessorParamType](
+			db, nil, step.EmptyDocProcessorParam, parallel.SortableId, step.NewOption(step.PagingRead, 2, 2))
+
+		_, err := w.HandleContext(context.Background(), parallel.NewParallel("faqs", db, 3),
+			ConsumerWorkerOptions(faqsQuery, "faqs", []string{"id", "title"}).
+				WithDestTable("faqs_copy", dialect.MySQL).
+				WithWriteMode(DeleteInsertMode, "id"))
+
+		assert.True(t, er.IsKind(err, er.KindFatal))
+		assert.Contains(t, err.Error(), "SortableId")
+		assert.Empty(t, db.queried)
+	})
+}
+
+type faqDoc struct {
+	Id    int64  `db:"id" json:"id"`
+	Title string `db:"title" json:"title"`
+}
+
+func (f faqDoc) ToModel(*step.EmptyDocProcessorParamType) (*faqDoc, error) {
+	return &f, nil
+}
+
+// sortByDBMock is parallel.ParallelDB of the ids [min, max]
+type sortByDBMock struct {
+	min, max int64
+}
+
+func (m *sortByDBMock) GetSortBy(string) (parallel.Partition, error) {
+	return parallel.NewPartition(m.min, m.max, m.max-m.min+1), nil
+}
+
+// newFaqServer serves the ids [from, to] of the query in pages of ?offset=&limit=
+func newFaqServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from, _ := strconv.ParseInt(q.Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(q.Get("to"), 10, 64)
+		offset, _ := strconv.ParseInt(q.Get("offset"), 10, 64)
+		limit, _ := strconv.ParseInt(q.Get("limit"), 10, 64)
+
+		items := make([]faqDoc, 0)
+		for id := from + offset; id <= to && int64(len(items)) < limit; id++ {
+			items = append(items, faqDoc{Id: id, Title: fmt.Sprintf("t%d", id)})
+		}
+		_ = json.NewEncoder(w).Encode(items)
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func Test_SourceWorker(t *testing.T) {
+	stepOpt := step.NewOption(step.PagingRead, 3, 2)
+
+	t.Run("write the pages of an api into the files of the partitions", func(t *testing.T) {
+		srv := newFaqServer(t)
+		dir := t.TempDir()
+
+		source := reader.NewHttpSource[faqDoc](srv.URL, reader.HttpOption{
+			Client:     srv.Client(),
+			Pagination: reader.OffsetLimit,
+			PageSize:   3,
+			FromParam:  "from",
+			ToParam:    "to",
+		})
+
+		w := NewSourceWorker[faqDoc, faqDoc, struct{}, step.EmptyDocProcessorParamType](
+			source, nil, step.EmptyDocProcessorParam, parallel.AutoIncrementId, stepOpt)
+
+		workerOpt := WriterWorkerOptions(func() step.Writer[faqDoc, struct{}] {
+			return writer.NewFileDocWriter[faqDoc, struct{}](dir, writer.FileOption{Format: writer.NdjsonFormat})
+		})
+
+		result, err := w.HandleContext(context.Background(), parallel.NewParallel("faqs", &sortByDBMock{min: 1, max: 10}, 2), workerOpt)
+		assert.NoError(t, err)
+
+		assert.Equal(t, int64(10), result.TotalRow)
+		assert.Equal(t, int64(10), result.TotalAffected)
+		for _, p := range result.Partitions {
+			assert.Equal(t, PartitionCompleted, p.Status)
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, "pCtx0.ndjson"))
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"id\":1,\"title\":\"t1\"}\n{\"id\":2,\"title\":\"t2\"}\n{\"id\":3,\"title\":\"t3\"}\n{\"id\":4,\"title\":\"t4\"}\n{\"id\":5,\"title\":\"t5\"}\n", string(b))
+
+		b, err = os.ReadFile(filepath.Join(dir, "pCtx1.ndjson"))
+		assert.NoError(t, err)
+		assert.Equal(t, "{\"id\":6,\"title\":\"t6\"}\n{\"id\":7,\"title\":\"t7\"}\n{\"id\":8,\"title\":\"t8\"}\n{\"id\":9,\"title\":\"t9\"}\n{\"id\":10,\"title\":\"t10\"}\n", string(b))
+	})
+
+	t.Run("fail with the writer of other types before any partition starts", func(t *testing.T) {
+		source := reader.NewHttpSource[faqDoc]("http://127.0.0.1:0", reader.HttpOption{Pagination: reader.OffsetLimit, PageSize: 3})
+
+		w := NewSourceWorker[faqDoc, faqDoc, struct{}, step.EmptyDocProcessorParamType](
+			source, nil, step.EmptyDocProcessorParam, parallel.AutoIncrementId, stepOpt)
+
+		workerOpt := WriterWorkerOptions(func() step.Writer[*faqDoc, struct{}] {
+			return writer.NewFileDocWriter[*faqDoc, struct{}](t.TempDir(), writer.FileOption{Format: writer.NdjsonFormat})
+		})
+
+		result, err := w.HandleContext(context.Background(), parallel.NewParallel("faqs", &sortByDBMock{min: 1, max: 10}, 2), workerOpt)
+		assert.True(t, er.IsKind(err, er.KindFatal))
+		assert.Empty(t, result.Partitions)
+	})
+}